@@ -0,0 +1,216 @@
+// Package graceful 实现不停机重启：收到 SIGUSR2 时 fork-exec 新的可执行文件并把监听 fd 传给它，
+// 新进程接管监听并通过管道报告就绪后，旧进程停止 accept、处理完在途请求再退出
+package graceful
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvInheritFDs 继承的监听数量，监听 fd 从 3 开始依次排列
+	EnvInheritFDs = "ZINX_INHERIT_FDS"
+	// EnvReadyFD 就绪通知管道写端的 fd，新进程就绪后写入一个字节
+	EnvReadyFD = "ZINX_READY_FD"
+
+	defaultReadyTimeout = 10 * time.Second
+)
+
+var (
+	inheritOnce sync.Once
+	inheritMu   sync.Mutex
+	inherited   []net.Listener
+	inheritErr  error
+
+	readyOnce sync.Once
+)
+
+// Inherited 返回从父进程继承且尚未被 Take 取走的监听，非重启拉起的进程返回 nil
+func Inherited() ([]net.Listener, error) {
+	inheritOnce.Do(loadInherited)
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+	return append([]net.Listener(nil), inherited...), inheritErr
+}
+
+func loadInherited() {
+	raw := os.Getenv(EnvInheritFDs)
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		inheritErr = fmt.Errorf("graceful: invalid %s=%q", EnvInheritFDs, raw)
+		return
+	}
+	for i := 0; i < n; i++ {
+		f := os.NewFile(uintptr(3+i), "inherited-listener-"+strconv.Itoa(i))
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			inheritErr = fmt.Errorf("graceful: inherit fd %d: %w", 3+i, err)
+			return
+		}
+		inherited = append(inherited, l)
+	}
+}
+
+// Take 取走与 addr 匹配的继承监听（TCP 要求端口相同且 IP 相同或均为通配地址，unix 要求路径相同），
+// 每个监听只会被取走一次；没有匹配项时返回 nil
+func Take(network, addr string) ([]net.Listener, error) {
+	inheritOnce.Do(loadInherited)
+	if inheritErr != nil {
+		return nil, inheritErr
+	}
+	var match func(a net.Addr) bool
+	if network == "unix" {
+		match = func(a net.Addr) bool {
+			got, ok := a.(*net.UnixAddr)
+			return ok && got.Name == addr
+		}
+	} else {
+		want, err := net.ResolveTCPAddr(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		match = func(a net.Addr) bool {
+			got, ok := a.(*net.TCPAddr)
+			return ok && sameAddr(got, want)
+		}
+	}
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+	var taken, rest []net.Listener
+	for _, l := range inherited {
+		if match(l.Addr()) {
+			taken = append(taken, l)
+		} else {
+			rest = append(rest, l)
+		}
+	}
+	inherited = rest
+	return taken, nil
+}
+
+func sameAddr(a, b *net.TCPAddr) bool {
+	if a.Port != b.Port || b.Port == 0 {
+		return false
+	}
+	unspecified := func(ip net.IP) bool { return ip == nil || ip.IsUnspecified() }
+	if unspecified(a.IP) || unspecified(b.IP) {
+		return unspecified(a.IP) && unspecified(b.IP)
+	}
+	return a.IP.Equal(b.IP)
+}
+
+// Ready 新进程开始 accept 后调用，通知父进程可以退出；非重启拉起的进程调用无副作用
+func Ready() {
+	readyOnce.Do(func() {
+		raw := os.Getenv(EnvReadyFD)
+		if raw == "" {
+			return
+		}
+		fd, err := strconv.Atoi(raw)
+		if err != nil {
+			return
+		}
+		f := os.NewFile(uintptr(fd), "ready-pipe")
+		_, _ = f.Write([]byte{1})
+		_ = f.Close()
+	})
+}
+
+// Restarter 父进程侧：拉起新进程并等待其就绪
+type Restarter struct {
+	// Args 新进程的命令行参数，nil 时沿用当前进程的 os.Args[1:]
+	Args []string
+	// ReadyTimeout 等待新进程就绪的最长时间，默认 10s
+	ReadyTimeout time.Duration
+}
+
+// Restart 将 listeners 的 fd 传给新进程并等待其调用 Ready，超时或新进程提前退出时杀掉新进程并返回错误
+func (r *Restarter) Restart(listeners []net.Listener) (*os.Process, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*os.File, 0, len(listeners)+1)
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, l := range listeners {
+		fl, ok := l.(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, fmt.Errorf("graceful: listener %T cannot export fd", l)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	files = append(files, wr)
+
+	args := r.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(filterEnv(os.Environ()),
+		EnvInheritFDs+"="+strconv.Itoa(len(listeners)),
+		EnvReadyFD+"="+strconv.Itoa(3+len(listeners)),
+	)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	// 关闭父进程中的写端，新进程退出时读端才能读到 EOF
+	_ = wr.Close()
+	files = files[:len(files)-1]
+
+	timeout := r.ReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	_ = rd.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 1)
+	if _, err := rd.Read(buf); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, fmt.Errorf("graceful: new process not ready within %v", timeout)
+		}
+		return nil, fmt.Errorf("graceful: new process exited before ready: %w", err)
+	}
+	// 旧进程退出前由独立 goroutine 回收新进程，避免其先退出时留下僵尸进程
+	go func() { _ = cmd.Wait() }()
+	return cmd.Process, nil
+}
+
+// filterEnv 去掉当前进程自身继承来的标记，避免传递给新进程
+func filterEnv(env []string) []string {
+	out := env[:0:0]
+	for _, kv := range env {
+		if strings.HasPrefix(kv, EnvInheritFDs+"=") || strings.HasPrefix(kv, EnvReadyFD+"=") {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
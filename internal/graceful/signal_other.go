@@ -0,0 +1,6 @@
+//go:build !unix
+
+package graceful
+
+// NotifyRestart 当前平台没有 SIGUSR2，不支持信号触发重启
+func NotifyRestart(fn func()) (stop func()) { return func() {} }
@@ -0,0 +1,30 @@
+//go:build unix
+
+package graceful
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NotifyRestart 每次收到 SIGUSR2 时在独立 goroutine 中调用 fn，返回的函数用于取消监听
+func NotifyRestart(fn func()) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+				fn()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
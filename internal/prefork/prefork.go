@@ -0,0 +1,248 @@
+// Package prefork 实现 prefork 模式：父进程按 CPU 数派生子进程并负责监管，
+// 子进程各自以 SO_REUSEPORT 监听同一端口独立 accept，崩溃的子进程按指数退避重新拉起，
+// 连续快速崩溃达到上限时放弃监管并通过 Failed 通知父进程
+package prefork
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// EnvChild 子进程标记，父进程派生子进程时设置为 "1"
+const EnvChild = "ZINX_PREFORK_CHILD"
+
+const (
+	// defaultRestartDelay 子进程首次快速退出后重启前的等待时间，之后每次翻倍
+	defaultRestartDelay = time.Second
+	// maxRestartDelay 重启等待时间的上限
+	maxRestartDelay = 30 * time.Second
+	// defaultMaxFailures 子进程连续快速退出的默认次数上限
+	defaultMaxFailures = 5
+	// stableRun 子进程运行超过该时长后退出视为偶发崩溃，立即重启并清零连续失败计数
+	stableRun = 10 * time.Second
+)
+
+// IsChild 当前进程是否为 prefork 子进程
+func IsChild() bool { return os.Getenv(EnvChild) == "1" }
+
+// Master 父进程侧的子进程监管者
+type Master struct {
+	// Children 子进程数量，<= 0 时取 CPU 数
+	Children int
+	// Args 子进程的命令行参数，nil 时沿用当前进程的 os.Args[1:]
+	Args []string
+	// RestartDelay 子进程快速退出后首次重启的等待时间，<= 0 时取 1s
+	RestartDelay time.Duration
+	// MaxFailures 子进程连续快速退出的次数上限（如监听失败、配置错误），
+	// 达到后不再重启并关闭 Failed，<= 0 时取 5
+	MaxFailures int
+
+	mu       sync.Mutex
+	procs    map[int]*os.Process
+	stopping bool
+	quit     chan struct{}
+	failed   chan struct{}
+	err      error
+	wg       sync.WaitGroup
+}
+
+// Start 派生全部子进程，并为每个子进程启动一个监管 goroutine
+func (m *Master) Start() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	n := m.Children
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	args := m.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+	m.mu.Lock()
+	m.procs = make(map[int]*os.Process)
+	m.quit = make(chan struct{})
+	m.failed = make(chan struct{})
+	m.mu.Unlock()
+	for i := 0; i < n; i++ {
+		cmd, err := m.spawn(exe, args)
+		if err != nil {
+			_ = m.Stop(context.Background())
+			return err
+		}
+		m.wg.Add(1)
+		go m.supervise(exe, args, cmd)
+	}
+	return nil
+}
+
+func (m *Master) spawn(exe string, args []string) (*exec.Cmd, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopping {
+		return nil, fmt.Errorf("prefork: master stopping")
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), EnvChild+"=1")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	m.procs[cmd.Process.Pid] = cmd.Process
+	return cmd, nil
+}
+
+// supervise 等待子进程退出，非停止状态下重新拉起；快速退出时按指数退避等待，
+// 连续快速退出达到 MaxFailures 次后放弃
+func (m *Master) supervise(exe string, args []string, cmd *exec.Cmd) {
+	defer m.wg.Done()
+	failures := 0
+	for {
+		started := time.Now()
+		err := cmd.Wait()
+		m.mu.Lock()
+		delete(m.procs, cmd.Process.Pid)
+		stopping := m.stopping
+		m.mu.Unlock()
+		if stopping {
+			return
+		}
+		if time.Since(started) >= stableRun {
+			failures = 0
+			fmt.Printf("[PREFORK]child %d exited, err: %v, restarting\n", cmd.Process.Pid, err)
+		} else {
+			failures++
+			if failures >= m.maxFailures() {
+				m.fail(fmt.Errorf("prefork: child exited %d times in a row, last err: %v", failures, err))
+				return
+			}
+			delay := m.backoff(failures)
+			fmt.Printf("[PREFORK]child %d exited, err: %v, restarting in %v\n", cmd.Process.Pid, err, delay)
+			select {
+			case <-time.After(delay):
+			case <-m.quit:
+				return
+			}
+		}
+		next, err := m.spawn(exe, args)
+		if err != nil {
+			fmt.Printf("[PREFORK]restart child failed, err: %v\n", err)
+			return
+		}
+		cmd = next
+	}
+}
+
+func (m *Master) maxFailures() int {
+	if m.MaxFailures > 0 {
+		return m.MaxFailures
+	}
+	return defaultMaxFailures
+}
+
+// backoff 第 n 次连续快速退出后的重启等待时间
+func (m *Master) backoff(n int) time.Duration {
+	d := m.RestartDelay
+	if d <= 0 {
+		d = defaultRestartDelay
+	}
+	for i := 1; i < n && d < maxRestartDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxRestartDelay)
+}
+
+// fail 记录首个放弃监管的原因并关闭 Failed
+func (m *Master) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fmt.Printf("[PREFORK]%v, giving up\n", err)
+	if m.err == nil {
+		m.err = err
+		close(m.failed)
+	}
+}
+
+// Failed 任一子进程连续快速退出达到 MaxFailures 次时关闭，其余子进程仍在运行，调用方应随后调用 Stop
+func (m *Master) Failed() <-chan struct{} { return m.failed }
+
+// Err 返回放弃监管的原因，未放弃时返回 nil
+func (m *Master) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Pids 返回当前存活子进程的 pid
+func (m *Master) Pids() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pids := make([]int, 0, len(m.procs))
+	for pid := range m.procs {
+		pids = append(pids, pid)
+	}
+	return pids
+}
+
+// Stop 向全部子进程发送 SIGTERM 并等待退出，ctx 到期后强制 SIGKILL
+func (m *Master) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.stopping && m.quit != nil {
+		close(m.quit)
+	}
+	m.stopping = true
+	for _, p := range m.procs {
+		_ = p.Signal(syscall.SIGTERM)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		for _, p := range m.procs {
+			_ = p.Kill()
+		}
+		m.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// WatchParent 在子进程中调用：父进程退出（被重新托管）或收到 SIGTERM/SIGINT 时调用一次 fn
+func WatchParent(fn func()) {
+	ppid := os.Getppid()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sig:
+				fn()
+				return
+			case <-ticker.C:
+				if os.Getppid() != ppid {
+					fn()
+					return
+				}
+			}
+		}
+	}()
+}
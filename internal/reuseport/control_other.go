@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package reuseport
+
+import (
+	"errors"
+	"syscall"
+)
+
+func control(network, address string, c syscall.RawConn) error {
+	return errors.New("reuseport: SO_REUSEPORT is not supported on this platform")
+}
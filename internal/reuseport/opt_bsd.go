@@ -0,0 +1,7 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+
+package reuseport
+
+import "syscall"
+
+const reusePortOpt = syscall.SO_REUSEPORT
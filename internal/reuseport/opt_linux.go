@@ -0,0 +1,4 @@
+package reuseport
+
+// reusePortOpt SO_REUSEPORT 在 Linux 上的取值，syscall 包未导出该常量
+const reusePortOpt = 0xf
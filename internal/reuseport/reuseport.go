@@ -0,0 +1,14 @@
+// Package reuseport 创建设置了 SO_REUSEADDR 与 SO_REUSEPORT 的监听，
+// 多个监听（同进程或跨进程）可绑定同一端口，由内核在它们之间分发新连接
+package reuseport
+
+import (
+	"context"
+	"net"
+)
+
+// Listen 以 SO_REUSEPORT 监听 TCP 地址，network 为 tcp/tcp4/tcp6
+func Listen(network, addr string) (net.Listener, error) {
+	lc := net.ListenConfig{Control: control}
+	return lc.Listen(context.Background(), network, addr)
+}
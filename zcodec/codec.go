@@ -0,0 +1,59 @@
+// Package zcodec 消息编解码器：将业务结构体与 znet 消息数据互相转换，可按服务器切换编码格式
+package zcodec
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Codec 编解码器，实现须可并发使用
+type Codec interface {
+	// Name 编码名称，用于配置中按名称选择
+	Name() string
+	// Encode 将 v 编码为字节
+	Encode(v any) ([]byte, error)
+	// Decode 将 data 解码到 v，v 须为非 nil 指针
+	Decode(data []byte, v any) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Codec{}
+)
+
+func init() {
+	Register(JSON{})
+	Register(Gob{})
+	Register(Proto{})
+}
+
+// Register 按 Name 注册编解码器，同名覆盖
+func Register(c Codec) {
+	registryMu.Lock()
+	registry[c.Name()] = c
+	registryMu.Unlock()
+}
+
+// Get 按名称查找编解码器
+func Get(name string) (Codec, error) {
+	registryMu.RLock()
+	c, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("zcodec: unknown codec %q, registered: %v", name, Names())
+	}
+	return c, nil
+}
+
+// Names 返回已注册的编码名称
+func Names() []string {
+	registryMu.RLock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	registryMu.RUnlock()
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,127 @@
+package zcodec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type address struct {
+	City string `pb:"1" json:"city"`
+	Zip  uint32 `pb:"2,fixed" json:"zip"`
+}
+
+type user struct {
+	ID      int64      `pb:"1" json:"id"`
+	Name    string     `pb:"2" json:"name"`
+	Delta   int32      `pb:"3,zigzag" json:"delta"`
+	Hash    uint64     `pb:"4,fixed" json:"hash"`
+	Neg     int32      `pb:"5" json:"neg"`
+	Ratio   float32    `pb:"6" json:"ratio"`
+	Score   float64    `pb:"7" json:"score"`
+	OK      bool       `pb:"8" json:"ok"`
+	Raw     []byte     `pb:"9" json:"raw"`
+	Tags    []string   `pb:"10" json:"tags"`
+	Nums    []int64    `pb:"11,zigzag" json:"nums"`
+	Addr    *address   `pb:"12" json:"addr"`
+	Home    address    `pb:"13" json:"home"`
+	History []*address `pb:"14" json:"history"`
+	Level   uint8      `pb:"15" json:"level"`
+	Skipped string     `json:"-"`
+}
+
+func sampleUser() user {
+	return user{
+		ID: 1 << 40, Name: "gopher", Delta: -7, Hash: math.MaxUint64, Neg: -1,
+		Ratio: 1.5, Score: -2.25, OK: true, Raw: []byte{0, 1, 2},
+		Tags: []string{"a", "", "c"}, Nums: []int64{-1, 0, 300},
+		Addr: &address{City: "Shanghai", Zip: 200000}, Home: address{City: "Hangzhou"},
+		History: []*address{{City: "x"}, {Zip: 1}}, Level: 9,
+	}
+}
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	for _, name := range []string{"json", "gob", "proto"} {
+		c, err := Get(name)
+		if err != nil { t.Fatal(err) }
+		in := sampleUser()
+		data, err := c.Encode(&in)
+		if err != nil { t.Fatalf("%s encode: %v", name, err) }
+		var out user
+		if err := c.Decode(data, &out); err != nil { t.Fatalf("%s decode: %v", name, err) }
+		if !reflect.DeepEqual(in, out) { t.Fatalf("%s round trip mismatch:\n in %+v\nout %+v", name, in, out) }
+	}
+	if _, err := Get("xml"); err == nil { t.Fatalf("expected unknown codec error") }
+}
+
+// 与官方文档中的编码示例逐字节比对
+func TestProto_WireCompat(t *testing.T) {
+	type test1 struct{ A int32 `pb:"1"` }
+	type test2 struct{ B string `pb:"2"` }
+	type test3 struct{ C test1 `pb:"3"` }
+	type test4 struct{ D []int32 `pb:"4"` }
+	type test5 struct{ E int32 `pb:"1,zigzag"` }
+	cases := []struct {
+		v    any
+		want string
+	}{
+		{&test1{A: 150}, "089601"},
+		{&test2{B: "testing"}, "120774657374696e67"},
+		{&test3{C: test1{A: 150}}, "1a03089601"},
+		{&test4{D: []int32{3, 270, 86942}}, "2206038e029ea705"},
+		{&test5{E: -1}, "0801"},
+		{&test1{}, ""},
+	}
+	for _, c := range cases {
+		got, err := Proto{}.Encode(c.v)
+		if err != nil { t.Fatal(err) }
+		if hex.EncodeToString(got) != c.want { t.Fatalf("%T: got %x, want %s", c.v, got, c.want) }
+		out := reflect.New(reflect.TypeOf(c.v).Elem()).Interface()
+		if err := (Proto{}).Decode(got, out); err != nil { t.Fatal(err) }
+		if !reflect.DeepEqual(out, c.v) { t.Fatalf("%T: decoded %+v", c.v, out) }
+	}
+
+	// 非 packed 的 repeated 数值同样可以解码
+	var d test4
+	unpacked, _ := hex.DecodeString("2003208e02")
+	if err := (Proto{}).Decode(unpacked, &d); err != nil || !reflect.DeepEqual(d.D, []int32{3, 270}) { t.Fatalf("unpacked decode: %v %v", d.D, err) }
+}
+
+func TestProto_Errors(t *testing.T) {
+	type known struct{ A int32 `pb:"1"` }
+	// 未知字段（各种线格式）被跳过
+	data, _ := hex.DecodeString("089601" + "1003" + "1a026869" + "2501020304" + "290102030405060708")
+	var k known
+	if err := (Proto{}).Decode(data, &k); err != nil || k.A != 150 { t.Fatalf("skip unknown: %+v %v", k, err) }
+
+	if err := (Proto{}).Decode([]byte{0x08, 0x96}, &k); err == nil { t.Fatalf("expected truncated varint error") }
+	if err := (Proto{}).Decode([]byte{0x0a, 0x05, 'a'}, &k); err == nil { t.Fatalf("expected truncated bytes error") }
+	if err := (Proto{}).Decode([]byte{0x0a, 0x01, 'a'}, &k); err == nil { t.Fatalf("expected wire type mismatch error") }
+	if err := (Proto{}).Decode(nil, k); err == nil { t.Fatalf("expected non-pointer error") }
+
+	type dup struct {
+		A int32 `pb:"1"`
+		B int32 `pb:"1"`
+	}
+	if _, err := (Proto{}).Encode(dup{}); err == nil { t.Fatalf("expected duplicate field error") }
+	type badType struct{ M map[string]int `pb:"1"` }
+	if _, err := (Proto{}).Encode(badType{}); err == nil { t.Fatalf("expected unsupported type error") }
+	if _, err := (Proto{}).Encode(42); err == nil { t.Fatalf("expected non-struct error") }
+}
+
+func TestProto_DecodeResets(t *testing.T) {
+	u := sampleUser()
+	data, _ := Proto{}.Encode(&address{City: "only"})
+	var out address
+	out.Zip = 7
+	if err := (Proto{}).Decode(data, &out); err != nil { t.Fatal(err) }
+	if out.Zip != 0 || out.City != "only" { t.Fatalf("decode should reset target, got %+v", out) }
+	// []byte 字段不引用输入缓冲区
+	enc, _ := Proto{}.Encode(&u)
+	var dec user
+	_ = Proto{}.Decode(enc, &dec)
+	for i := range enc { enc[i] = 0 }
+	if !bytes.Equal(dec.Raw, []byte{0, 1, 2}) { t.Fatalf("decoded bytes alias input buffer") }
+}
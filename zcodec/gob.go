@@ -0,0 +1,24 @@
+package zcodec
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Gob 基于 encoding/gob 的编解码器。每条消息独立编码并携带类型描述，
+// 适合 Go 服务之间通信，不适合对体积敏感的场景
+type Gob struct{}
+
+func (Gob) Name() string { return "gob" }
+
+func (Gob) Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (Gob) Decode(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
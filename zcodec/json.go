@@ -0,0 +1,10 @@
+package zcodec
+
+import "encoding/json"
+
+// JSON 基于 encoding/json 的编解码器
+type JSON struct{}
+
+func (JSON) Name() string                    { return "json" }
+func (JSON) Encode(v any) ([]byte, error)    { return json.Marshal(v) }
+func (JSON) Decode(data []byte, v any) error { return json.Unmarshal(data, v) }
@@ -0,0 +1,470 @@
+package zcodec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Proto 手写的 protobuf 线格式编解码器，按结构体字段的 pb 标签编码，无需生成代码：
+//
+//	type User struct {
+//		ID    int64    `pb:"1"`
+//		Name  string   `pb:"2"`
+//		Delta int32    `pb:"3,zigzag"` // sint32
+//		Hash  uint64   `pb:"4,fixed"`  // fixed64
+//		Tags  []string `pb:"5"`
+//		Addr  *Address `pb:"6"`        // 嵌套消息
+//	}
+//
+// 支持 bool、整数、浮点、string、[]byte、嵌套结构体（或其指针）以及它们的切片（repeated），
+// 数值切片按 packed 编码，解码时兼容非 packed 格式；不支持 map、oneof 与 group。
+// 与 proto3 一致，标量零值不编码，未知字段在解码时跳过
+type Proto struct{}
+
+func (Proto) Name() string { return "proto" }
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+
+	maxFieldNum = 1<<29 - 1
+)
+
+var (
+	errTruncated = errors.New("zcodec: proto: truncated data")
+	errOverflow  = errors.New("zcodec: proto: varint overflow")
+)
+
+// protoField 结构体字段的编码信息
+type protoField struct {
+	num    uint64
+	index  int
+	name   string
+	zigzag bool // sint32/sint64
+	fixed  bool // fixed32/fixed64/sfixed32/sfixed64
+}
+
+type protoStruct struct {
+	fields []protoField
+	byNum  map[uint64]*protoField
+}
+
+var protoCache sync.Map // reflect.Type -> *protoStruct
+
+func protoInfo(t reflect.Type) (*protoStruct, error) {
+	if v, ok := protoCache.Load(t); ok {
+		return v.(*protoStruct), nil
+	}
+	info := &protoStruct{byNum: make(map[uint64]*protoField)}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("pb")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		num, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil || num == 0 || num > maxFieldNum {
+			return nil, fmt.Errorf("zcodec: proto: %s.%s: invalid field number %q", t, sf.Name, parts[0])
+		}
+		f := protoField{num: num, index: i, name: sf.Name}
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "zigzag":
+				f.zigzag = true
+			case "fixed":
+				f.fixed = true
+			default:
+				return nil, fmt.Errorf("zcodec: proto: %s.%s: unknown option %q", t, sf.Name, opt)
+			}
+		}
+		if !protoSupported(sf.Type, true) {
+			return nil, fmt.Errorf("zcodec: proto: %s.%s: unsupported type %s", t, sf.Name, sf.Type)
+		}
+		for _, prev := range info.fields {
+			if prev.num == num {
+				return nil, fmt.Errorf("zcodec: proto: %s: duplicate field number %d", t, num)
+			}
+		}
+		info.fields = append(info.fields, f)
+	}
+	// fields 追加完成后再建立索引，避免扩容使指针失效
+	for i := range info.fields {
+		info.byNum[info.fields[i].num] = &info.fields[i]
+	}
+	v, _ := protoCache.LoadOrStore(t, info)
+	return v.(*protoStruct), nil
+}
+
+// protoSupported 判断类型能否编码，allowSlice 为 false 时不允许再嵌套切片（[]byte 除外）
+func protoSupported(t reflect.Type, allowSlice bool) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Struct:
+		return true
+	case reflect.Pointer:
+		return t.Elem().Kind() == reflect.Struct
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return true
+		}
+		return allowSlice && protoSupported(t.Elem(), false)
+	}
+	return false
+}
+
+// isScalar 可 packed 编码的标量类型
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func isBytes(t reflect.Type) bool { return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 }
+
+// scalarWire 标量类型对应的线格式
+func scalarWire(f *protoField, k reflect.Kind) uint64 {
+	switch k {
+	case reflect.Float32:
+		return wireFixed32
+	case reflect.Float64:
+		return wireFixed64
+	case reflect.Bool:
+		return wireVarint
+	}
+	if !f.fixed {
+		return wireVarint
+	}
+	switch k {
+	case reflect.Int64, reflect.Uint64, reflect.Int, reflect.Uint:
+		return wireFixed64
+	}
+	return wireFixed32
+}
+
+// structValue 取出 v 指向的结构体
+func structValue(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return reflect.Value{}, fmt.Errorf("zcodec: proto: nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("zcodec: proto: unsupported type %T, want struct", v)
+	}
+	return rv, nil
+}
+
+func (Proto) Encode(v any) ([]byte, error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return appendStruct(nil, rv)
+}
+
+func (Proto) Decode(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("zcodec: proto: Decode needs non-nil struct pointer, got %T", v)
+	}
+	rv = rv.Elem()
+	rv.Set(reflect.Zero(rv.Type()))
+	return decodeStruct(data, rv)
+}
+
+// --- 编码 ---
+
+func appendVarint(b []byte, x uint64) []byte { return binary.AppendUvarint(b, x) }
+
+func appendTag(b []byte, num, wire uint64) []byte { return appendVarint(b, num<<3|wire) }
+
+func appendStruct(b []byte, v reflect.Value) ([]byte, error) {
+	info, err := protoInfo(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	for i := range info.fields {
+		f := &info.fields[i]
+		if b, err = appendField(b, f, v.Field(f.index)); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendField(b []byte, f *protoField, v reflect.Value) ([]byte, error) {
+	t := v.Type()
+	if t.Kind() != reflect.Slice || isBytes(t) {
+		return appendValue(b, f, v, false)
+	}
+	if v.Len() == 0 {
+		return b, nil
+	}
+	elemKind := t.Elem().Kind()
+	if isScalar(elemKind) {
+		// packed：所有元素编码为一个长度前缀字段
+		var payload []byte
+		for i := 0; i < v.Len(); i++ {
+			payload = appendScalar(payload, f, v.Index(i))
+		}
+		b = appendTag(b, f.num, wireBytes)
+		b = appendVarint(b, uint64(len(payload)))
+		return append(b, payload...), nil
+	}
+	var err error
+	for i := 0; i < v.Len(); i++ {
+		if b, err = appendValue(b, f, v.Index(i), true); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// appendValue 编码单个值，always 为 false 时零值不编码（repeated 元素始终编码）
+func appendValue(b []byte, f *protoField, v reflect.Value, always bool) ([]byte, error) {
+	switch k := v.Kind(); {
+	case isScalar(k):
+		if !always && v.IsZero() {
+			return b, nil
+		}
+		b = appendTag(b, f.num, scalarWire(f, k))
+		return appendScalar(b, f, v), nil
+	case k == reflect.String:
+		if !always && v.Len() == 0 {
+			return b, nil
+		}
+		b = appendTag(b, f.num, wireBytes)
+		b = appendVarint(b, uint64(v.Len()))
+		return append(b, v.String()...), nil
+	case k == reflect.Slice: // []byte
+		if !always && v.Len() == 0 {
+			return b, nil
+		}
+		b = appendTag(b, f.num, wireBytes)
+		b = appendVarint(b, uint64(v.Len()))
+		return append(b, v.Bytes()...), nil
+	case k == reflect.Pointer:
+		if v.IsNil() {
+			if !always {
+				return b, nil
+			}
+			// repeated 中的 nil 按空消息编码
+			return appendVarint(appendTag(b, f.num, wireBytes), 0), nil
+		}
+		return appendMessage(b, f, v.Elem(), true)
+	case k == reflect.Struct:
+		return appendMessage(b, f, v, always)
+	}
+	return nil, fmt.Errorf("zcodec: proto: field %s: unsupported kind %s", f.name, v.Kind())
+}
+
+func appendMessage(b []byte, f *protoField, v reflect.Value, always bool) ([]byte, error) {
+	sub, err := appendStruct(nil, v)
+	if err != nil {
+		return nil, err
+	}
+	if !always && len(sub) == 0 {
+		return b, nil
+	}
+	b = appendTag(b, f.num, wireBytes)
+	b = appendVarint(b, uint64(len(sub)))
+	return append(b, sub...), nil
+}
+
+// appendScalar 编码标量的值部分（不含 tag）
+func appendScalar(b []byte, f *protoField, v reflect.Value) []byte {
+	switch k := v.Kind(); k {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case reflect.Float32:
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.Float()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x := v.Int()
+		switch {
+		case f.zigzag:
+			return appendVarint(b, uint64(x<<1)^uint64(x>>63))
+		case f.fixed && scalarWire(f, k) == wireFixed32:
+			return binary.LittleEndian.AppendUint32(b, uint32(x))
+		case f.fixed:
+			return binary.LittleEndian.AppendUint64(b, uint64(x))
+		}
+		return appendVarint(b, uint64(x))
+	default: // 无符号整数
+		x := v.Uint()
+		switch {
+		case f.fixed && scalarWire(f, k) == wireFixed32:
+			return binary.LittleEndian.AppendUint32(b, uint32(x))
+		case f.fixed:
+			return binary.LittleEndian.AppendUint64(b, x)
+		}
+		return appendVarint(b, x)
+	}
+}
+
+// --- 解码 ---
+
+func consumeVarint(b []byte) (uint64, int, error) {
+	x, n := binary.Uvarint(b)
+	switch {
+	case n == 0:
+		return 0, 0, errTruncated
+	case n < 0:
+		return 0, 0, errOverflow
+	}
+	return x, n, nil
+}
+
+// consumeValue 读取一个字段值：定长与 varint 返回数值，长度前缀返回负载
+func consumeValue(b []byte, wire uint64) (val uint64, payload []byte, n int, err error) {
+	switch wire {
+	case wireVarint:
+		val, n, err = consumeVarint(b)
+	case wireFixed64:
+		if len(b) < 8 {
+			return 0, nil, 0, errTruncated
+		}
+		val, n = binary.LittleEndian.Uint64(b), 8
+	case wireFixed32:
+		if len(b) < 4 {
+			return 0, nil, 0, errTruncated
+		}
+		val, n = uint64(binary.LittleEndian.Uint32(b)), 4
+	case wireBytes:
+		var size uint64
+		if size, n, err = consumeVarint(b); err != nil {
+			return
+		}
+		if size > uint64(len(b)-n) {
+			return 0, nil, 0, errTruncated
+		}
+		payload = b[n : n+int(size)]
+		n += int(size)
+	default:
+		err = fmt.Errorf("zcodec: proto: unsupported wire type %d", wire)
+	}
+	return
+}
+
+func decodeStruct(b []byte, v reflect.Value) error {
+	info, err := protoInfo(v.Type())
+	if err != nil {
+		return err
+	}
+	for len(b) > 0 {
+		tag, n, err := consumeVarint(b)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+		num, wire := tag>>3, tag&7
+		if num == 0 {
+			return fmt.Errorf("zcodec: proto: invalid field number 0")
+		}
+		val, payload, n, err := consumeValue(b, wire)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+		f, ok := info.byNum[num]
+		if !ok {
+			continue // 未知字段
+		}
+		if err := decodeField(f, v.Field(f.index), wire, val, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeField(f *protoField, v reflect.Value, wire, val uint64, payload []byte) error {
+	t := v.Type()
+	if t.Kind() != reflect.Slice || isBytes(t) {
+		return decodeValue(f, v, wire, val, payload)
+	}
+	elem := reflect.New(t.Elem()).Elem()
+	if ek := t.Elem().Kind(); isScalar(ek) && wire == wireBytes {
+		// packed
+		ew := scalarWire(f, ek)
+		for len(payload) > 0 {
+			x, _, n, err := consumeValue(payload, ew)
+			if err != nil {
+				return err
+			}
+			payload = payload[n:]
+			if err := decodeValue(f, elem, ew, x, nil); err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, elem))
+		}
+		return nil
+	}
+	if err := decodeValue(f, elem, wire, val, payload); err != nil {
+		return err
+	}
+	v.Set(reflect.Append(v, elem))
+	return nil
+}
+
+func decodeValue(f *protoField, v reflect.Value, wire, val uint64, payload []byte) error {
+	k := v.Kind()
+	want := uint64(wireBytes)
+	if isScalar(k) {
+		want = scalarWire(f, k)
+	}
+	if wire != want {
+		return fmt.Errorf("zcodec: proto: field %s: wire type %d, want %d", f.name, wire, want)
+	}
+	switch k {
+	case reflect.Bool:
+		v.SetBool(val != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case f.zigzag:
+			v.SetInt(int64(val>>1) ^ -int64(val&1))
+		case wire == wireFixed32:
+			v.SetInt(int64(int32(uint32(val))))
+		default:
+			v.SetInt(int64(val))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(val)
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(uint32(val))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(val))
+	case reflect.String:
+		v.SetString(string(payload))
+	case reflect.Slice: // []byte，拷贝以免引用调用方缓冲区
+		v.SetBytes(append([]byte{}, payload...))
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeStruct(payload, v.Elem())
+	case reflect.Struct:
+		return decodeStruct(payload, v)
+	default:
+		return fmt.Errorf("zcodec: proto: field %s: unsupported kind %s", f.name, k)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package zconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/SparkleBo/zinx/zcodec"
+)
+
+// EnvPrefix 环境变量覆盖项的统一前缀，如 ZINX_PORT、ZINX_TLS_CERT_FILE
+const EnvPrefix = "ZINX_"
+
+// Config 服务端配置，znet 与 zhttp/std 共用
+//
+// 加载顺序：默认值 -> JSON 文件 -> 环境变量，后者覆盖前者。
+type Config struct {
+	Name      string `json:"name" env:"NAME"`
+	IPVersion string `json:"ip_version" env:"IP_VERSION"`
+	Host      string `json:"host" env:"HOST"`
+	Port      int    `json:"port" env:"PORT"`
+
+	// Network znet 监听的网络类型："tcp"（默认）、"unix" 或 "udp"
+	Network string `json:"network" env:"NETWORK"`
+	// SocketPath unix socket 文件路径，SocketPerm 为八进制权限（如 "0660"），空表示沿用 umask
+	SocketPath string `json:"socket_path" env:"SOCKET_PATH"`
+	SocketPerm string `json:"socket_perm" env:"SOCKET_PERM"`
+	// UDPSessionTimeout udp 伪会话的空闲超时，0 表示默认 1 分钟
+	UDPSessionTimeout Duration `json:"udp_session_timeout" env:"UDP_SESSION_TIMEOUT"`
+
+	// 连接与工作池
+	MaxConn          int `json:"max_conn" env:"MAX_CONN"`
+	MaxMsgChanLen    int `json:"max_msg_chan_len" env:"MAX_MSG_CHAN_LEN"`
+	WorkerPoolSize   int `json:"worker_pool_size" env:"WORKER_POOL_SIZE"`
+	MaxWorkerTaskLen int `json:"max_worker_task_len" env:"MAX_WORKER_TASK_LEN"`
+
+	// MaxPacketSize 单条消息数据部分的最大字节数，0 表示不限制
+	MaxPacketSize uint32 `json:"max_packet_size" env:"MAX_PACKET_SIZE"`
+
+	// Transport znet 连接 I/O 模型："goroutine"（默认）或 "epoll"（仅 Linux）
+	Transport string `json:"transport" env:"TRANSPORT"`
+	// Codec znet 消息数据的编码名称："json"（默认）、"gob"、"proto" 或自行注册的编码
+	Codec string `json:"codec" env:"CODEC"`
+	// EventLoops epoll 模式下的事件循环数量，0 表示取 GOMAXPROCS
+	EventLoops int `json:"event_loops" env:"EVENT_LOOPS"`
+
+	// ReusePort > 0 时以 SO_REUSEPORT 创建该数量的监听
+	ReusePort int `json:"reuse_port" env:"REUSE_PORT"`
+	// Prefork 启用 prefork 模式，PreforkChildren 为子进程数量，0 表示取 CPU 数
+	Prefork         bool `json:"prefork" env:"PREFORK"`
+	PreforkChildren int  `json:"prefork_children" env:"PREFORK_CHILDREN"`
+	// GracefulRestart 收到 SIGUSR2 时拉起新进程接管监听，旧进程处理完在途请求后退出
+	GracefulRestart bool `json:"graceful_restart" env:"GRACEFUL_RESTART"`
+
+	ReadTimeout  Duration `json:"read_timeout" env:"READ_TIMEOUT"`
+	WriteTimeout Duration `json:"write_timeout" env:"WRITE_TIMEOUT"`
+
+	TLS TLSConfig `json:"tls" env:"TLS_"`
+}
+
+// TLSConfig 证书配置，CertFile 与 KeyFile 同时设置时启用 TLS
+type TLSConfig struct {
+	CertFile string `json:"cert_file" env:"CERT_FILE"`
+	KeyFile  string `json:"key_file" env:"KEY_FILE"`
+	// ClientCAFile 设置时启用双向认证：要求客户端出示由其中 CA 签发的证书
+	ClientCAFile string `json:"client_ca_file" env:"CLIENT_CA_FILE"`
+}
+
+// Enabled 是否配置了证书
+func (t TLSConfig) Enabled() bool { return t.CertFile != "" && t.KeyFile != "" }
+
+// Load 按证书文件构造服务端 tls.Config，设置 ClientCAFile 时要求并校验客户端证书
+func (t TLSConfig) Load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("zconfig: load tls key pair: %w", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if t.ClientCAFile != "" {
+		pool, err := LoadCertPool(t.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// LoadCertPool 读取 PEM 文件中的全部证书构造证书池
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("zconfig: read %s: %w", path, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("zconfig: no certificate found in %s", path)
+	}
+	return pool, nil
+}
+
+// Duration 支持 JSON 中以 "5s"、"200ms" 字符串或纳秒整数表示时长
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration { return time.Duration(d) }
+
+func (d Duration) MarshalJSON() ([]byte, error) { return json.Marshal(time.Duration(d).String()) }
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch x := v.(type) {
+	case float64:
+		*d = Duration(x)
+		return nil
+	case string:
+		dur, err := time.ParseDuration(x)
+		if err != nil {
+			return err
+		}
+		*d = Duration(dur)
+		return nil
+	default:
+		return fmt.Errorf("zconfig: invalid duration %s", b)
+	}
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Name:             "zinx",
+		IPVersion:        "tcp4",
+		Host:             "127.0.0.1",
+		Port:             8888,
+		MaxConn:          12000,
+		MaxMsgChanLen:    1024,
+		WorkerPoolSize:   runtime.NumCPU(),
+		MaxWorkerTaskLen: 1024,
+		MaxPacketSize:    4096,
+	}
+}
+
+// Load 在默认值基础上加载 JSON 文件（path 为空则跳过）并应用环境变量覆盖
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("zconfig: read %s: %w", path, err)
+		}
+		if err := json.Unmarshal(b, cfg); err != nil {
+			return nil, fmt.Errorf("zconfig: parse %s: %w", path, err)
+		}
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ApplyEnv 按字段的 env 标签读取 EnvPrefix 前缀的环境变量覆盖当前值
+func (c *Config) ApplyEnv() error {
+	return applyEnv(reflect.ValueOf(c).Elem(), EnvPrefix)
+}
+
+// Validate 校验配置的基本合法性
+func (c *Config) Validate() error {
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("zconfig: invalid port %d", c.Port)
+	}
+	switch c.Network {
+	case "", "tcp":
+	case "udp":
+		// 未启用工作池时处理函数运行在全部会话共用的读循环中
+		if c.WorkerPoolSize <= 0 {
+			return errors.New("zconfig: udp network requires worker_pool_size > 0")
+		}
+	case "unix":
+		if c.SocketPath == "" {
+			return errors.New("zconfig: unix network requires socket_path")
+		}
+	default:
+		return fmt.Errorf("zconfig: unknown network %q", c.Network)
+	}
+	if _, err := c.SocketFileMode(); err != nil {
+		return err
+	}
+	if c.Transport != "" && c.Transport != "goroutine" && c.Transport != "epoll" {
+		return fmt.Errorf("zconfig: unknown transport %q", c.Transport)
+	}
+	if c.Codec != "" {
+		if _, err := zcodec.Get(c.Codec); err != nil {
+			return err
+		}
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("zconfig: tls cert_file and key_file must be set together")
+	}
+	if c.TLS.ClientCAFile != "" && !c.TLS.Enabled() {
+		return errors.New("zconfig: tls client_ca_file requires cert_file and key_file")
+	}
+	return nil
+}
+
+// SocketFileMode 解析 SocketPerm，未设置时返回 0
+func (c *Config) SocketFileMode() (os.FileMode, error) {
+	if c.SocketPerm == "" {
+		return 0, nil
+	}
+	perm, err := strconv.ParseUint(c.SocketPerm, 8, 32)
+	if err != nil || perm > 0o777 {
+		return 0, fmt.Errorf("zconfig: invalid socket_perm %q", c.SocketPerm)
+	}
+	return os.FileMode(perm), nil
+}
+
+// Addr 返回 host:port 形式的监听地址
+func (c *Config) Addr() string { return net.JoinHostPort(c.Host, strconv.Itoa(c.Port)) }
+
+var durationType = reflect.TypeOf(Duration(0))
+
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			if err := applyEnv(f, prefix+tag); err != nil {
+				return err
+			}
+			continue
+		}
+		key := prefix + tag
+		raw, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setField(f, raw); err != nil {
+			return fmt.Errorf("zconfig: env %s=%q: %w", key, raw, err)
+		}
+	}
+	return nil
+}
+
+func setField(f reflect.Value, raw string) error {
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field kind %s", f.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package zconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad_FileAndEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zinx.json")
+	data := `{"name":"game","host":"0.0.0.0","port":9000,"max_conn":100,"read_timeout":"5s","write_timeout":2000000000,"tls":{"cert_file":"a.crt","key_file":"a.key"}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil { t.Fatal(err) }
+
+	t.Setenv("ZINX_PORT", "9100")
+	t.Setenv("ZINX_WORKER_POOL_SIZE", "3")
+	t.Setenv("ZINX_MAX_PACKET_SIZE", "65536")
+	t.Setenv("ZINX_TLS_KEY_FILE", "b.key")
+
+	cfg, err := Load(path)
+	if err != nil { t.Fatal(err) }
+	if cfg.Name != "game" || cfg.MaxConn != 100 { t.Fatalf("file values not loaded: %+v", cfg) }
+	if cfg.Port != 9100 || cfg.WorkerPoolSize != 3 || cfg.MaxPacketSize != 65536 { t.Fatalf("env overrides not applied: %+v", cfg) }
+	if cfg.ReadTimeout.Std() != 5*time.Second || cfg.WriteTimeout.Std() != 2*time.Second { t.Fatalf("durations: %v %v", cfg.ReadTimeout, cfg.WriteTimeout) }
+	if cfg.TLS.CertFile != "a.crt" || cfg.TLS.KeyFile != "b.key" || !cfg.TLS.Enabled() { t.Fatalf("tls: %+v", cfg.TLS) }
+	if cfg.Addr() != "0.0.0.0:9100" { t.Fatalf("addr: %s", cfg.Addr()) }
+	// 未出现在文件与环境变量中的字段保留默认值
+	if cfg.MaxWorkerTaskLen != Default().MaxWorkerTaskLen { t.Fatalf("default lost: %d", cfg.MaxWorkerTaskLen) }
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Setenv("ZINX_PORT", "not-a-number")
+	if _, err := Load(""); err == nil { t.Fatalf("expected env parse error") }
+
+	t.Setenv("ZINX_PORT", "70000")
+	if _, err := Load(""); err == nil { t.Fatalf("expected invalid port error") }
+
+	t.Setenv("ZINX_PORT", "80")
+	t.Setenv("ZINX_CODEC", "xml")
+	if _, err := Load(""); err == nil { t.Fatalf("expected unknown codec error") }
+
+	t.Setenv("ZINX_CODEC", "proto")
+	t.Setenv("ZINX_TLS_CERT_FILE", "only.crt")
+	if _, err := Load(""); err == nil { t.Fatalf("expected tls pair error") }
+
+	t.Setenv("ZINX_TLS_CERT_FILE", "")
+	t.Setenv("ZINX_TLS_CLIENT_CA_FILE", "ca.crt")
+	if _, err := Load(""); err == nil { t.Fatalf("expected client ca without cert error") }
+
+	t.Setenv("ZINX_TLS_CLIENT_CA_FILE", "")
+	t.Setenv("ZINX_NETWORK", "sctp")
+	if _, err := Load(""); err == nil { t.Fatalf("expected unknown network error") }
+	t.Setenv("ZINX_NETWORK", "unix")
+	if _, err := Load(""); err == nil { t.Fatalf("expected missing socket path error") }
+	t.Setenv("ZINX_SOCKET_PATH", "/tmp/zinx.sock")
+	t.Setenv("ZINX_SOCKET_PERM", "0999")
+	if _, err := Load(""); err == nil { t.Fatalf("expected invalid socket perm error") }
+	t.Setenv("ZINX_SOCKET_PERM", "0660")
+	cfg, err := Load("")
+	if err != nil { t.Fatal(err) }
+	if perm, _ := cfg.SocketFileMode(); perm != 0o660 { t.Fatalf("socket perm: %o", perm) }
+
+	t.Setenv("ZINX_NETWORK", "udp")
+	t.Setenv("ZINX_WORKER_POOL_SIZE", "0")
+	if _, err := Load(""); err == nil { t.Fatalf("expected udp without worker pool error") }
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil { t.Fatalf("expected missing file error") }
+}
@@ -3,11 +3,15 @@ package std
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/zpool"
 )
 
 // StdContext 基于 net/http 的上下文实现
@@ -23,6 +27,18 @@ var ctxPool = sync.Pool{New: func() any {
     return &StdContext{storage: make(map[string]any), params: make(map[string]string)}
 }}
 
+// jsonEncoder 绑定池化缓冲区的 JSON 编码器，随缓冲区一起复用
+type jsonEncoder struct {
+    buf zpool.Buffer
+    enc *json.Encoder
+}
+
+var jsonPool = sync.Pool{New: func() any {
+    e := &jsonEncoder{}
+    e.enc = json.NewEncoder(&e.buf)
+    return e
+}}
+
 // AcquireContext 从对象池获取并初始化请求相关字段
 func AcquireContext(w http.ResponseWriter, r *http.Request) *StdContext {
     c := ctxPool.Get().(*StdContext)
@@ -60,6 +76,18 @@ func (c *StdContext) Err() error { return c.r.Context().Err() }
 func (c *StdContext) Method() string { return c.r.Method }
 func (c *StdContext) Path() string { return c.r.URL.Path }
 func (c *StdContext) Param(name string) string { return c.params[name] }
+func (c *StdContext) ParamInt(name string) (int, error) {
+    v, ok := c.params[name]
+    if !ok { return 0, fmt.Errorf("std: param %q not found", name) }
+    n, err := strconv.Atoi(v)
+    if err != nil { return 0, fmt.Errorf("std: param %q: %w", name, err) }
+    return n, nil
+}
+func (c *StdContext) ParamUUID(name string) (ziface.UUID, error) {
+    v, ok := c.params[name]
+    if !ok { return ziface.UUID{}, fmt.Errorf("std: param %q not found", name) }
+    return ziface.ParseUUID(v)
+}
 func (c *StdContext) Query(key string) string { return c.r.URL.Query().Get(key) }
 
 // Shared state
@@ -70,17 +98,31 @@ func (c *StdContext) Get(key string) (any, bool) {
 }
 
 // Renderers
+// JSON 先编码到池化缓冲区再一次性写出，编码失败时不会写出半截响应
 func (c *StdContext) JSON(code int, v any) error {
+    e := jsonPool.Get().(*jsonEncoder)
+    defer func() {
+        // 过大的缓冲区不放回，避免长期占用内存
+        if cap(e.buf.B) > zpool.MaxSize {
+            e.buf.B = nil
+        }
+        e.buf.Reset()
+        jsonPool.Put(e)
+    }()
+    if err := e.enc.Encode(v); err != nil {
+        return err
+    }
     c.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    c.w.Header().Set("Content-Length", strconv.Itoa(e.buf.Len()))
     c.w.WriteHeader(code)
-    enc := json.NewEncoder(c.w)
-    return enc.Encode(v)
+    _, err := e.buf.WriteTo(c.w)
+    return err
 }
 
 func (c *StdContext) String(code int, s string) error {
     c.w.Header().Set("Content-Type", "text/plain; charset=utf-8")
     c.w.WriteHeader(code)
-    _, err := c.w.Write([]byte(s))
+    _, err := io.WriteString(c.w, s)
     return err
 }
 
@@ -24,4 +24,6 @@ func (r *Router) Find(method, path string) (ziface.Handler, map[string]string, [
     return r.inner.Find(method, path)
 }
 
+func (r *Router) Allowed(path string) []string { return r.inner.Allowed(path) }
+
 var _ ziface.Router = (*Router)(nil)
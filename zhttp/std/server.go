@@ -2,10 +2,20 @@ package std
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/SparkleBo/zinx/internal/graceful"
+	"github.com/SparkleBo/zinx/internal/prefork"
+	"github.com/SparkleBo/zinx/internal/reuseport"
+	"github.com/SparkleBo/zinx/zconfig"
 	"github.com/SparkleBo/zinx/ziface"
 	"github.com/SparkleBo/zinx/zrouter"
 )
@@ -16,12 +26,60 @@ type Server struct {
     router     ziface.Router
     mws        []ziface.Middleware
     httpServer *http.Server
+
+    // 可选：读写超时与 TLS 证书，由 NewWithConfig 设置
+    readTimeout  time.Duration
+    writeTimeout time.Duration
+    certFile     string
+    keyFile      string
+    // clientCAFile 非空时要求并校验客户端证书
+    clientCAFile string
+
+    // reusePort > 0 时以 SO_REUSEPORT 创建多个监听；master 非 nil 表示启用 prefork
+    reusePort int
+    master    *prefork.Master
+    listeners []net.Listener
+    // restarter 非 nil 表示启用不停机重启
+    restarter *graceful.Restarter
+
+    stopOnce sync.Once
+    done     chan struct{}
 }
 
 func New(addr string) *Server {
-    return &Server{addr: addr, router: zrouter.New()}
+    return &Server{addr: addr, router: zrouter.New(), done: make(chan struct{})}
 }
 
+// NewWithConfig 按配置构造服务器：监听地址、读写超时与 TLS 证书
+func NewWithConfig(cfg *zconfig.Config) *Server {
+    s := New(cfg.Addr())
+    s.readTimeout = cfg.ReadTimeout.Std()
+    s.writeTimeout = cfg.WriteTimeout.Std()
+    if cfg.TLS.Enabled() {
+        s.certFile, s.keyFile = cfg.TLS.CertFile, cfg.TLS.KeyFile
+        s.clientCAFile = cfg.TLS.ClientCAFile
+    }
+    s.reusePort = cfg.ReusePort
+    if cfg.Prefork {
+        s.SetPrefork(cfg.PreforkChildren)
+    }
+    if cfg.GracefulRestart {
+        s.SetGracefulRestart()
+    }
+    return s
+}
+
+// SetReusePort 以 SO_REUSEPORT 创建 n 个绑定同一端口的监听，须在 Start 之前调用
+func (s *Server) SetReusePort(n int) { s.reusePort = n }
+
+// SetPrefork 启用 prefork 模式：父进程派生 children 个子进程（<= 0 时取 CPU 数）并监管，
+// 子进程以 SO_REUSEPORT 监听同一端口；须在 Start 之前调用
+func (s *Server) SetPrefork(children int) { s.master = &prefork.Master{Children: children} }
+
+// SetGracefulRestart 启用不停机重启：Serve 期间收到 SIGUSR2 时拉起新进程并传递监听 fd，
+// 新进程就绪后当前进程优雅停止；须在 Start 之前调用
+func (s *Server) SetGracefulRestart() { s.restarter = &graceful.Restarter{} }
+
 // Use 注册全局中间件
 func (s *Server) Use(mws ...ziface.Middleware) { s.mws = append(s.mws, mws...) }
 
@@ -40,46 +98,230 @@ func (s *Server) Start() {
     if s.httpServer != nil {
         return
     }
-    handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        h, params, mws, ok := s.router.Find(r.Method, r.URL.Path)
-        if !ok {
-            http.NotFound(w, r)
+    // prefork 父进程只负责派生与监管子进程
+    if s.master != nil && !prefork.IsChild() {
+        if err := s.master.Start(); err != nil {
+            fmt.Printf("[ERROR] prefork start: %v\n", err)
             return
         }
-        ctx := AcquireContext(w, r)
-        if len(params) > 0 { ctx.AttachParams(params) }
-        final := chain(h, append(s.mws, mws...)...)
-        if err := final(ctx); err != nil {
-            _ = ctx.String(http.StatusInternalServerError, fmt.Sprintf("internal error: %v", err))
+        go s.watchMaster()
+        return
+    }
+    s.httpServer = &http.Server{Addr: s.addr, Handler: s, ReadTimeout: s.readTimeout, WriteTimeout: s.writeTimeout}
+    if s.clientCAFile != "" {
+        pool, err := zconfig.LoadCertPool(s.clientCAFile)
+        if err != nil {
+            fmt.Printf("[ERROR] http server tls: %v\n", err)
+            return
         }
-        ReleaseContext(ctx)
-    })
-
-    s.httpServer = &http.Server{Addr: s.addr, Handler: handler}
-    go func() {
-        fmt.Printf("[HTTP] Listening on %s\n", s.addr)
-        if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-            fmt.Printf("[ERROR] http server listen: %v\n", err)
+        s.httpServer.TLSConfig = &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+    }
+    listeners, err := s.listen()
+    if err != nil {
+        fmt.Printf("[ERROR] http server listen: %v\n", err)
+        return
+    }
+    if listeners == nil {
+        go func() {
+            fmt.Printf("[HTTP] Listening on %s\n", s.addr)
+            var err error
+            if s.certFile != "" {
+                err = s.httpServer.ListenAndServeTLS(s.certFile, s.keyFile)
+            } else {
+                err = s.httpServer.ListenAndServe()
+            }
+            if err != nil && err != http.ErrServerClosed {
+                fmt.Printf("[ERROR] http server listen: %v\n", err)
+            }
+        }()
+        return
+    }
+    s.listeners = listeners
+    for _, l := range listeners {
+        go func(l net.Listener) {
+            var err error
+            if s.certFile != "" {
+                err = s.httpServer.ServeTLS(l, s.certFile, s.keyFile)
+            } else {
+                err = s.httpServer.Serve(l)
+            }
+            if err != nil && err != http.ErrServerClosed {
+                fmt.Printf("[ERROR] http server serve: %v\n", err)
+            }
+        }(l)
+    }
+    fmt.Printf("[HTTP] Listening on %s with %d listeners\n", listeners[0].Addr(), len(listeners))
+    // 由不停机重启拉起时通知旧进程可以退出
+    if s.restarter != nil {
+        graceful.Ready()
+    }
+}
+
+// listen 需要自行管理监听时返回监听列表，否则返回 nil 交给 ListenAndServe：
+// 启用不停机重启时优先接管父进程传入的监听；SO_REUSEPORT 或 prefork 子进程创建共享端口的监听
+func (s *Server) listen() ([]net.Listener, error) {
+    if s.restarter != nil {
+        inherited, err := graceful.Take("tcp", s.addr)
+        if err != nil {
+            return nil, err
+        }
+        if len(inherited) > 0 {
+            return inherited, nil
         }
-    }()
+    }
+    n := s.reusePort
+    if n <= 0 && s.master != nil {
+        n = 1
+    }
+    if n <= 0 {
+        if s.restarter == nil {
+            return nil, nil
+        }
+        l, err := net.Listen("tcp", s.addr)
+        if err != nil {
+            return nil, err
+        }
+        return []net.Listener{l}, nil
+    }
+    addr := s.addr
+    listeners := make([]net.Listener, 0, n)
+    for i := 0; i < n; i++ {
+        l, err := reuseport.Listen("tcp", addr)
+        if err != nil {
+            for _, l := range listeners {
+                _ = l.Close()
+            }
+            return nil, err
+        }
+        // 端口为 0 时后续监听绑定到第一个监听分配到的端口
+        addr = l.Addr().String()
+        listeners = append(listeners, l)
+    }
+    return listeners, nil
+}
+
+// Restart 不停机重启：拉起新进程并把监听交给它，新进程就绪后优雅停止当前服务器；
+// 新进程启动失败时当前服务器继续运行。需启用 SetGracefulRestart，prefork 模式下不支持
+func (s *Server) Restart() error {
+    if s.restarter == nil {
+        return errors.New("std: graceful restart not enabled")
+    }
+    if s.master != nil {
+        return errors.New("std: graceful restart not supported in prefork mode")
+    }
+    if len(s.listeners) == 0 {
+        return errors.New("std: server not listening")
+    }
+    proc, err := s.restarter.Restart(s.listeners)
+    if err != nil {
+        return err
+    }
+    fmt.Printf("[HTTP] new process %d ready, draining\n", proc.Pid)
+    s.Stop()
+    return nil
 }
 
-// Stop 优雅停止 HTTP 服务器
+// Stop 优雅停止 HTTP 服务器，prefork 父进程则停止全部子进程
 func (s *Server) Stop() {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    defer s.stopOnce.Do(func() { close(s.done) })
+    if s.master != nil && !prefork.IsChild() {
+        if err := s.master.Stop(ctx); err != nil {
+            fmt.Printf("[ERROR] prefork stop: %v\n", err)
+        }
+        return
+    }
     if s.httpServer == nil {
         return
     }
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-    defer cancel()
     if err := s.httpServer.Shutdown(ctx); err != nil {
         fmt.Printf("[ERROR] http server shutdown: %v\n", err)
     }
 }
 
-// Serve 启动并阻塞当前 goroutine
+// watchMaster prefork 父进程在子进程反复崩溃、放弃监管后自行停止，Serve 随之返回，原因见 Err
+func (s *Server) watchMaster() {
+    select {
+    case <-s.master.Failed():
+    case <-s.done:
+        return
+    }
+    fmt.Printf("[ERROR] prefork children keep crashing: %v\n", s.master.Err())
+    s.Stop()
+}
+
+// Err 返回服务器自行停止的原因，目前仅有 prefork 子进程反复崩溃一种情况
+func (s *Server) Err() error {
+    if s.master == nil {
+        return nil
+    }
+    return s.master.Err()
+}
+
+// Serve 启动并阻塞当前 goroutine，直到 Stop 被调用或 prefork 父进程放弃监管
 func (s *Server) Serve() {
     s.Start()
-    select {}
+    // prefork 子进程随父进程退出或收到终止信号时优雅停止
+    if s.master != nil && prefork.IsChild() {
+        prefork.WatchParent(s.Stop)
+    }
+    if s.restarter != nil {
+        stop := graceful.NotifyRestart(func() {
+            if err := s.Restart(); err != nil {
+                fmt.Printf("[ERROR] graceful restart: %v\n", err)
+            }
+        })
+        defer stop()
+    }
+    <-s.done
+}
+
+// ServeHTTP 按路由分发请求：未显式注册 HEAD 时由同路径的 GET 路由处理（响应体由 net/http 丢弃）；
+// 路径存在但方法不匹配时返回 405 并带上 Allow 头，OPTIONS 请求自动以 204 与 Allow 头应答。
+// 自动生成的 405/OPTIONS 响应同样经过全局中间件，便于 CORS 等中间件处理预检请求
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    h, params, mws, ok := s.router.Find(r.Method, r.URL.Path)
+    if !ok && r.Method == http.MethodHead {
+        h, params, mws, ok = s.router.Find(http.MethodGet, r.URL.Path)
+    }
+    if !ok {
+        allow := allowHeader(s.router.Allowed(r.URL.Path))
+        if allow == "" {
+            http.NotFound(w, r)
+            return
+        }
+        h = func(ziface.Context) error {
+            w.Header().Set("Allow", allow)
+            if r.Method == http.MethodOptions {
+                w.WriteHeader(http.StatusNoContent)
+            } else {
+                http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+            }
+            return nil
+        }
+    }
+    ctx := AcquireContext(w, r)
+    if len(params) > 0 { ctx.AttachParams(params) }
+    final := chain(h, append(s.mws, mws...)...)
+    if err := final(ctx); err != nil {
+        _ = ctx.String(http.StatusInternalServerError, fmt.Sprintf("internal error: %v", err))
+    }
+    ReleaseContext(ctx)
+}
+
+// allowHeader 在已注册方法基础上补充隐含支持的 HEAD（有 GET 时）与 OPTIONS，没有任何方法时返回空串
+func allowHeader(methods []string) string {
+    if len(methods) == 0 { return "" }
+    set := map[string]bool{http.MethodOptions: true}
+    for _, m := range methods {
+        set[m] = true
+        if m == http.MethodGet { set[http.MethodHead] = true }
+    }
+    all := make([]string, 0, len(set))
+    for m := range set { all = append(all, m) }
+    sort.Strings(all)
+    return strings.Join(all, ", ")
 }
 
 // chain 构造中间件调用链，按注册顺序应用
@@ -1,9 +1,17 @@
 package std
 
 import (
+	"fmt"
+	"io"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/SparkleBo/zinx/internal/prefork"
+	"github.com/SparkleBo/zinx/zconfig"
 	"github.com/SparkleBo/zinx/ziface"
 )
 
@@ -61,6 +69,154 @@ func TestRouter_Group(t *testing.T) {
     if !ok || h == nil { t.Fatalf("group route should match via parent router") }
 }
 
+func TestRouter_NamedWildcard(t *testing.T) {
+    s := New("127.0.0.1:0")
+    s.Route("GET", "/static/*filepath", func(ctx ziface.Context) error { return ctx.String(200, "file="+ctx.Param("filepath")) })
+    for path, want := range map[string]string{
+        "/static/css/app.css": "file=css/app.css",
+        "/static/":            "file=",
+        "/static":             "file=",
+    } {
+        rr := httptest.NewRecorder()
+        s.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+        if rr.Code != 200 || rr.Body.String() != want { t.Fatalf("%s: expected %q, got %d %q", path, want, rr.Code, rr.Body.String()) }
+    }
+}
+
+func TestContext_TypedParams(t *testing.T) {
+    s := New("127.0.0.1:0")
+    s.Route("GET", "/users/:id<int>", func(ctx ziface.Context) error {
+        id, err := ctx.ParamInt("id")
+        if err != nil { return err }
+        if _, err := ctx.ParamUUID("id"); err == nil { t.Fatalf("int param should not parse as uuid") }
+        if _, err := ctx.ParamInt("missing"); err == nil { t.Fatalf("missing param should fail") }
+        return ctx.String(200, fmt.Sprintf("id=%d", id+1))
+    })
+    s.Route("GET", "/users/:uid<uuid>", func(ctx ziface.Context) error {
+        u, err := ctx.ParamUUID("uid")
+        if err != nil { return err }
+        return ctx.String(200, "uuid="+u.String())
+    })
+    for path, want := range map[string]string{
+        "/users/41": "id=42",
+        "/users/6F9619FF-8B86-D011-B42D-00C04FC964FF": "uuid=6f9619ff-8b86-d011-b42d-00c04fc964ff",
+    } {
+        rr := httptest.NewRecorder()
+        s.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+        if rr.Code != 200 || rr.Body.String() != want { t.Fatalf("%s: expected %q, got %d %q", path, want, rr.Code, rr.Body.String()) }
+    }
+    // 两个约束都不满足时没有路由匹配
+    rr := httptest.NewRecorder()
+    s.ServeHTTP(rr, httptest.NewRequest("GET", "/users/bob", nil))
+    if rr.Code != 404 { t.Fatalf("expected 404, got %d", rr.Code) }
+}
+
+// --- Server unit tests ---
+
+func TestServer_NewWithConfig(t *testing.T) {
+    cfg := zconfig.Default()
+    cfg.Host, cfg.Port = "0.0.0.0", 9090
+    cfg.ReadTimeout = zconfig.Duration(3 * time.Second)
+    cfg.TLS = zconfig.TLSConfig{CertFile: "a.crt", KeyFile: "a.key"}
+    s := NewWithConfig(cfg)
+    if s.addr != "0.0.0.0:9090" { t.Fatalf("addr: %s", s.addr) }
+    if s.readTimeout != 3*time.Second || s.writeTimeout != 0 { t.Fatalf("timeouts: %v %v", s.readTimeout, s.writeTimeout) }
+    if s.certFile != "a.crt" || s.keyFile != "a.key" { t.Fatalf("tls files not applied") }
+}
+
+func TestServer_ReusePort(t *testing.T) {
+    s := New("127.0.0.1:0")
+    s.SetReusePort(2)
+    s.Route("GET", "/ping", func(ctx ziface.Context) error { return ctx.String(200, "pong") })
+    s.Start()
+    if len(s.listeners) != 2 { t.Fatalf("expected 2 listeners, got %d", len(s.listeners)) }
+    if s.listeners[0].Addr().String() != s.listeners[1].Addr().String() { t.Fatalf("listeners bound to different addrs") }
+    for i := 0; i < 4; i++ {
+        resp, err := http.Get("http://" + s.listeners[0].Addr().String() + "/ping")
+        if err != nil { t.Fatal(err) }
+        body, _ := io.ReadAll(resp.Body)
+        resp.Body.Close()
+        if string(body) != "pong" { t.Fatalf("unexpected body %q", body) }
+    }
+    served := make(chan struct{})
+    go func() { <-s.done; close(served) }()
+    s.Stop()
+    select {
+    case <-served:
+    case <-time.After(3 * time.Second):
+        t.Fatalf("Serve would not return after Stop")
+    }
+}
+
+// TestServer_PreforkCrashLoop 子进程启动即退出时父进程按退避重启，达到上限后放弃并让 Serve 返回
+func TestServer_PreforkCrashLoop(t *testing.T) {
+    if prefork.IsChild() { os.Exit(3) }
+    s := New("127.0.0.1:0")
+    s.SetPrefork(1)
+    s.master.Args = []string{"-test.run=^TestServer_PreforkCrashLoop$"}
+    s.master.RestartDelay = 10 * time.Millisecond
+    s.master.MaxFailures = 3
+
+    done := make(chan struct{})
+    go func() {
+        s.Serve()
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(10 * time.Second):
+        t.Fatalf("master kept restarting a crashing child")
+    }
+    if err := s.Err(); err == nil || !strings.Contains(err.Error(), "3 times in a row") { t.Fatalf("unexpected err %v", err) }
+    if pids := s.master.Pids(); len(pids) != 0 { t.Fatalf("children left running: %v", pids) }
+}
+
+func TestServer_MethodNotAllowed(t *testing.T) {
+    s := New("127.0.0.1:0")
+    var mwCalls int
+    s.Use(func(next ziface.Handler) ziface.Handler {
+        return func(ctx ziface.Context) error { mwCalls++; return next(ctx) }
+    })
+    s.Route("GET", "/users/:id", func(ctx ziface.Context) error { return ctx.String(200, "user "+ctx.Param("id")) })
+    s.Route("DELETE", "/users/:id", func(ctx ziface.Context) error { return ctx.String(204, "") })
+    s.Route("POST", "/users", func(ctx ziface.Context) error { return ctx.String(201, "created") })
+    s.Route("HEAD", "/explicit", func(ctx ziface.Context) error { return ctx.String(299, "") })
+    s.Route("GET", "/explicit", func(ctx ziface.Context) error { return ctx.String(200, "get") })
+    srv := httptest.NewServer(s)
+    defer srv.Close()
+
+    do := func(method, path string) (*http.Response, string) {
+        req, err := http.NewRequest(method, srv.URL+path, nil)
+        if err != nil { t.Fatal(err) }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil { t.Fatal(err) }
+        defer resp.Body.Close()
+        body, _ := io.ReadAll(resp.Body)
+        return resp, string(body)
+    }
+
+    resp, _ := do("PUT", "/users/7")
+    if resp.StatusCode != 405 { t.Fatalf("expected 405, got %d", resp.StatusCode) }
+    if got := resp.Header.Get("Allow"); got != "DELETE, GET, HEAD, OPTIONS" { t.Fatalf("unexpected Allow %q", got) }
+    if resp, _ = do("GET", "/users"); resp.StatusCode != 405 || resp.Header.Get("Allow") != "OPTIONS, POST" {
+        t.Fatalf("expected 405 with POST allowed, got %d %q", resp.StatusCode, resp.Header.Get("Allow"))
+    }
+    if resp, _ = do("GET", "/missing"); resp.StatusCode != 404 || resp.Header.Get("Allow") != "" { t.Fatalf("expected plain 404, got %d", resp.StatusCode) }
+
+    // OPTIONS 自动应答，并经过全局中间件
+    mwCalls = 0
+    resp, body := do("OPTIONS", "/users/7")
+    if resp.StatusCode != 204 || body != "" { t.Fatalf("expected 204 for OPTIONS, got %d %q", resp.StatusCode, body) }
+    if got := resp.Header.Get("Allow"); got != "DELETE, GET, HEAD, OPTIONS" { t.Fatalf("unexpected Allow %q", got) }
+    if mwCalls != 1 { t.Fatalf("global middleware should wrap automatic responses, called %d", mwCalls) }
+
+    // HEAD 由 GET 路由处理且不返回响应体；显式注册的 HEAD 优先
+    resp, body = do("HEAD", "/users/7")
+    if resp.StatusCode != 200 || body != "" || resp.Header.Get("Content-Type") == "" { t.Fatalf("HEAD via GET: %d %q", resp.StatusCode, body) }
+    if resp, _ = do("HEAD", "/explicit"); resp.StatusCode != 299 { t.Fatalf("explicit HEAD route should win, got %d", resp.StatusCode) }
+    if resp, body = do("GET", "/users/7"); resp.StatusCode != 200 || body != "user 7" { t.Fatalf("GET: %d %q", resp.StatusCode, body) }
+}
+
 // --- Context unit tests ---
 
 func TestContext_Renderers(t *testing.T) {
@@ -17,6 +17,10 @@ type Context interface {
     Method() string
     Path() string
     Param(name string) string
+    // ParamInt/ParamUUID 读取并转换路由参数，参数不存在或格式不符时返回错误；
+    // 路由使用 :name<int>、:name<uuid> 约束时转换一定成功
+    ParamInt(name string) (int, error)
+    ParamUUID(name string) (UUID, error)
     Query(key string) string
 
     // 共享状态
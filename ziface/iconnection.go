@@ -0,0 +1,37 @@
+package ziface
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+)
+
+// IConnection 服务端持有的一条客户端连接
+type IConnection interface {
+	// Start 启动连接的读写业务
+	Start()
+	// Close 关闭连接，可重复调用
+	Close()
+
+	GetConnID() uint64
+	// GetConn 返回底层连接，事件循环等不基于 net.Conn 的传输返回 nil
+	GetConn() net.Conn
+	RemoteAddr() net.Addr
+	// PeerCertificate 返回 TLS 握手中已校验的对端证书（服务端即双向认证下的客户端证书），
+	// 非 TLS 连接或对端未出示证书时返回 nil
+	PeerCertificate() *x509.Certificate
+	// Context 连接级上下文，连接关闭时取消
+	Context() context.Context
+
+	// Send 同步发送一条消息，返回时数据已写入 socket
+	Send(msgID uint32, data []byte) error
+	// SendBuffered 将消息放入发送队列，由写协程异步发送，队列满时按溢出策略处理
+	SendBuffered(msgID uint32, data []byte) error
+	// SendMsg 语义同 SendBuffered，按服务器封包器封包完整消息，供包头带有额外字段的协议使用
+	SendMsg(msg IMessage) error
+
+	// 连接属性，可在任意 goroutine 中读写
+	SetProperty(key string, val any)
+	GetProperty(key string) (any, bool)
+	RemoveProperty(key string)
+}
@@ -0,0 +1,13 @@
+package ziface
+
+// IConnManager 连接管理：记录所有存活连接，支持按 ID 查找与统一关闭
+type IConnManager interface {
+	Add(conn IConnection)
+	Remove(conn IConnection)
+	Get(connID uint64) (IConnection, error)
+	Len() int
+	// Range 遍历当前连接快照，fn 返回 false 时停止
+	Range(fn func(conn IConnection) bool)
+	// ClearConn 关闭并移除全部连接
+	ClearConn()
+}
@@ -0,0 +1,11 @@
+package ziface
+
+// IDataPack 封包/拆包接口，负责消息与字节流之间的转换，可按协议替换实现
+type IDataPack interface {
+	// GetHeadLen 返回包头长度，读循环据此先读出包头
+	GetHeadLen() uint32
+	// Pack 将消息编码为完整的一帧（包头 + 数据）
+	Pack(msg IMessage) ([]byte, error)
+	// Unpack 解析包头，返回仅含 MsgID 与数据长度的消息，数据由调用方继续读取
+	Unpack(head []byte) (IMessage, error)
+}
@@ -0,0 +1,15 @@
+package ziface
+
+// IGroupManager 连接分组（房间、频道、租户），一个连接可同时属于多个组
+type IGroupManager interface {
+	// Join 将连接加入组，连接已关闭时返回错误
+	Join(group string, conn IConnection) error
+	Leave(group string, conn IConnection)
+	// LeaveAll 将连接移出所有组，连接关闭时自动调用
+	LeaveAll(conn IConnection)
+	Members(group string) []IConnection
+	Groups() []string
+	// Broadcast 向组内所有连接推送消息，不阻塞调用方，返回成功入队的连接数；
+	// 发送队列已满的慢接收方会被跳过，只计入实际入队的连接
+	Broadcast(group string, msgID uint32, data []byte) int
+}
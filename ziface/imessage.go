@@ -0,0 +1,12 @@
+package ziface
+
+// IMessage 将一帧 TLV 数据抽象为消息：MsgID + 数据长度 + 数据
+type IMessage interface {
+	GetMsgID() uint32
+	GetDataLen() uint32
+	GetData() []byte
+
+	SetMsgID(id uint32)
+	SetDataLen(n uint32)
+	SetData(data []byte)
+}
@@ -0,0 +1,18 @@
+package ziface
+
+// MsgHandler 消息处理函数，按 MsgID 注册
+type MsgHandler func(req IRequest) error
+
+// IMsgHandle 消息路由：维护 MsgID 到处理函数的映射并负责分发
+type IMsgHandle interface {
+	AddRouter(msgID uint32, h MsgHandler)
+	// DoMsgHandler 在当前 goroutine 中同步执行处理函数
+	DoMsgHandler(req IRequest)
+
+	// StartWorkerPool 启动工作池，未启动时 SendMsgToTaskQueue 退化为同步执行
+	StartWorkerPool()
+	// StopWorkerPool 停止工作池，已入队的任务会先执行完
+	StopWorkerPool()
+	// SendMsgToTaskQueue 将请求投递到工作池，同一连接的请求保证按序执行
+	SendMsgToTaskQueue(req IRequest) error
+}
@@ -0,0 +1,9 @@
+package ziface
+
+// ProtocolStats 协议错误计数，自服务器创建起累计
+type ProtocolStats struct {
+	// Oversize 数据长度超过上限的帧
+	Oversize uint64
+	// Malformed 包头无法解析或数据未读完整的帧
+	Malformed uint64
+}
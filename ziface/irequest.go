@@ -0,0 +1,15 @@
+package ziface
+
+// IRequest 将客户端连接与一条已解码的消息绑定在一起，交给消息处理器
+type IRequest interface {
+	GetConnection() IConnection
+	GetMsgID() uint32
+	// GetMessage 返回封包器拆出的原始消息，自定义封包器可据此读取额外的包头字段
+	GetMessage() IMessage
+	// GetData 返回的切片可能来自缓冲池，仅在处理函数返回前有效，需要保留时请拷贝
+	GetData() []byte
+	// Bind 按服务器配置的编解码器将消息数据解码到 v，v 须为非 nil 指针
+	Bind(v any) error
+	// Reply 按服务器配置的编解码器编码 v，以 msgID 发送回该连接并等待写出
+	Reply(msgID uint32, v any) error
+}
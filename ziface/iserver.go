@@ -1,7 +1,34 @@
 package ziface
 
+import (
+	"context"
+	"net"
+)
+
 type IServer interface {
 	Start()
-	Stop()
+	// Stop 优雅停止：不再接受新连接，等待在途消息处理完并关闭连接，
+	// ctx 到期时强制关闭剩余连接并返回 ctx.Err()
+	Stop(ctx context.Context) error
+	// Serve 启动并阻塞，直到 Stop 完成
 	Serve()
-}
\ No newline at end of file
+	// AddRouter 为指定 MsgID 注册处理函数
+	AddRouter(msgID uint32, h MsgHandler)
+	// GetConnMgr 返回连接管理器
+	GetConnMgr() IConnManager
+	// GetGroupMgr 返回连接分组管理器
+	GetGroupMgr() IGroupManager
+	// Broadcast 向全部连接推送消息，不阻塞调用方，返回成功入队的连接数
+	Broadcast(msgID uint32, data []byte) int
+
+	// SetOnAccept 在连接建立、读循环启动前调用，返回非 nil 错误则拒绝并关闭该连接
+	SetOnAccept(fn func(conn net.Conn) error)
+	// SetOnConnStart 在连接读循环开始前调用，可用于鉴权与初始化连接属性
+	SetOnConnStart(fn func(conn IConnection))
+	// SetOnConnStop 在连接关闭时调用，连接属性仍可读取
+	SetOnConnStop(fn func(conn IConnection))
+	// SetOnProtocolError 在收到超限或畸形帧时调用，随后按服务器配置的处理方式关闭连接或跳过该帧
+	SetOnProtocolError(fn func(conn IConnection, err error))
+	// ProtocolStats 返回协议错误计数
+	ProtocolStats() ProtocolStats
+}
@@ -6,4 +6,6 @@ type Router interface {
     Group(prefix string, mws ...Middleware) Router
     // Find 根据方法与路径解析到处理器、参数与中间件
     Find(method, path string) (Handler, map[string]string, []Middleware, bool)
+    // Allowed 返回 path 在哪些方法下注册了路由（已排序），用于 405 响应与 Allow 头
+    Allowed(path string) []string
 }
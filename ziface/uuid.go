@@ -0,0 +1,34 @@
+package ziface
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// UUID 128 位 UUID，由 Context.ParamUUID 返回
+type UUID [16]byte
+
+// String 返回小写的 8-4-4-4-12 形式
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	hex.Encode(b[9:13], u[4:6])
+	hex.Encode(b[14:18], u[6:8])
+	hex.Encode(b[19:23], u[8:10])
+	hex.Encode(b[24:], u[10:])
+	b[8], b[13], b[18], b[23] = '-', '-', '-', '-'
+	return string(b[:])
+}
+
+// ParseUUID 解析 8-4-4-4-12 形式的 UUID，不区分大小写
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("ziface: invalid uuid %q", s)
+	}
+	src := s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(src)); err != nil {
+		return UUID{}, fmt.Errorf("ziface: invalid uuid %q", s)
+	}
+	return u, nil
+}
@@ -3,6 +3,7 @@ package zmw
 import (
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/SparkleBo/zinx/zhttp/std"
 	"github.com/SparkleBo/zinx/ziface"
@@ -44,3 +45,20 @@ func TestRateLimit(t *testing.T) {
     if rr2.Code != 429 { t.Fatalf("expected 429, got %d", rr2.Code) }
 }
 
+func TestRateLimit_Rate(t *testing.T) {
+    for _, rps := range []int{30, 150} {
+        // 桶容量足以容纳一个 tick 内补充的令牌，避免桶满丢弃影响计数
+        const burst = 10
+        h := RateLimit(rps, burst)(func(ctx ziface.Context) error { return ctx.String(200, "ok") })
+        allowed := 0
+        start := time.Now()
+        for time.Since(start) < time.Second {
+            rr := httptest.NewRecorder()
+            _ = h(std.NewContext(rr, httptest.NewRequest("GET", "/", nil)))
+            if rr.Code == 200 { allowed++ }
+            time.Sleep(time.Millisecond)
+        }
+        got := allowed - burst
+        if got < rps*85/100 || got > rps*115/100 { t.Fatalf("RateLimit(%d) allowed %d req/s", rps, got) }
+    }
+}
@@ -4,6 +4,7 @@ import (
     "time"
 
     "github.com/SparkleBo/zinx/ziface"
+    "github.com/SparkleBo/zinx/ztimer"
 )
 
 // RateLimit 简易令牌桶限流
@@ -16,18 +17,25 @@ func RateLimit(rps int, burst int) ziface.Middleware {
     // 预热填满桶
     for i := 0; i < burst; i++ { tokens <- struct{}{} }
 
-    // 按固定速率补充令牌
-    interval := time.Second / time.Duration(rps)
-    ticker := time.NewTicker(interval)
-    go func() {
-        for range ticker.C {
+    // 按实际经过的时间补充令牌：注册到共享时间轮，多个实例共用同一驱动 goroutine。
+    // 时间轮间隔会向上取整到 tick，按触发次数计数会偏少，因此按时间折算，不足一个的部分留到下次
+    interval := max(time.Second/time.Duration(rps), ztimer.Default().Tick())
+    last := time.Now()
+    var pending float64
+    ztimer.Every(interval, func() {
+        now := time.Now()
+        pending += now.Sub(last).Seconds() * float64(rps)
+        last = now
+        for ; pending >= 1; pending-- {
             select {
             case tokens <- struct{}{}:
             default:
                 // 桶满，丢弃令牌
+                pending = 0
+                return
             }
         }
-    }()
+    })
 
     return func(next ziface.Handler) ziface.Handler {
         return func(ctx ziface.Context) error {
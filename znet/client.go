@@ -0,0 +1,328 @@
+package znet
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+var ErrNotConnected = errors.New("znet: client not connected")
+
+// BackoffConfig 断线重连的指数退避配置
+type BackoffConfig struct {
+	// Initial 首次重试前的等待时间，默认 100ms
+	Initial time.Duration
+	// Max 等待时间上限，默认 30s
+	Max time.Duration
+	// Multiplier 每次失败后等待时间的倍数，默认 2
+	Multiplier float64
+	// Jitter 随机抖动比例（0~1），实际等待时间在 [d*(1-Jitter), d] 内均匀分布，
+	// 避免大量客户端同时重连，默认 0.2
+	Jitter float64
+	// Stable 连接保持超过该时长后断开才清零退避计数，更早断开视为一次失败，默认 5s
+	Stable time.Duration
+}
+
+func (b *BackoffConfig) normalize() {
+	if b.Initial <= 0 {
+		b.Initial = 100 * time.Millisecond
+	}
+	if b.Max <= 0 {
+		b.Max = 30 * time.Second
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = 2
+	}
+	if b.Jitter <= 0 || b.Jitter > 1 {
+		b.Jitter = 0.2
+	}
+	if b.Stable <= 0 {
+		b.Stable = 5 * time.Second
+	}
+}
+
+// delay 第 attempt 次（从 0 开始）连续失败后的等待时间
+func (b *BackoffConfig) delay(attempt int) time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < attempt && d < float64(b.Max); i++ {
+		d *= b.Multiplier
+	}
+	d = min(d, float64(b.Max))
+	return time.Duration(d * (1 - b.Jitter*rand.Float64()))
+}
+
+// Client znet 客户端：与服务端使用相同的封包、MsgID 路由、发送队列与心跳实现，
+// 连接断开后按指数退避自动重连，直到 Stop
+type Client struct {
+	Name    string
+	Network string
+	Addr    string
+
+	// core 不监听端口的服务器实例，承载连接所需的封包器、路由、工作池与心跳
+	core         *Server
+	connOpts     []Option
+	backoff      BackoffConfig
+	dialTimeout  time.Duration
+	tlsConfig    *tls.Config
+	reconnect    bool
+	onConnect    func(conn ziface.IConnection)
+	onDisconnect func(conn ziface.IConnection)
+	onDialError  func(err error, attempt int, wait time.Duration)
+
+	mu        sync.RWMutex
+	conn      *Connection
+	connected chan struct{} // 当前连接建立时关闭，断开后重新创建
+
+	quit    chan struct{}
+	done    chan struct{}
+	started bool
+	stopped sync.Once
+}
+
+// ClientOption 客户端可选项
+type ClientOption func(c *Client)
+
+// WithConnOptions 设置连接相关的服务器选项，与服务端共用：WithPacket、WithCodec、WithHeartbeat、
+// WithMaxPacketSize、WithTimeouts、WithWorkerPool、WithOverloadPolicy、WithMaxMsgChanLen、
+// WithSendHighWater、WithOverflowPolicy；监听、传输层与进程模型相关的选项对客户端无效
+func WithConnOptions(opts ...Option) ClientOption {
+	return func(c *Client) { c.connOpts = append(c.connOpts, opts...) }
+}
+
+// WithBackoff 设置重连退避参数
+func WithBackoff(b BackoffConfig) ClientOption {
+	return func(c *Client) { c.backoff = b }
+}
+
+// WithReconnect 是否在连接断开后自动重连，默认开启
+func WithReconnect(on bool) ClientOption {
+	return func(c *Client) { c.reconnect = on }
+}
+
+// WithDialTimeout 设置单次拨号超时，默认 5s
+func WithDialTimeout(d time.Duration) ClientOption {
+	return func(c *Client) { c.dialTimeout = d }
+}
+
+// WithClientTLS 以 TLS 连接服务端，cfg.ServerName 为空时取 Addr 中的主机名；
+// 服务端要求双向认证时在 cfg.Certificates 中提供客户端证书
+func WithClientTLS(cfg *tls.Config) ClientOption {
+	return func(c *Client) { c.tlsConfig = cfg }
+}
+
+// WithOnConnect 连接建立、读循环开始前调用，可用于登录鉴权等初始化
+func WithOnConnect(fn func(conn ziface.IConnection)) ClientOption {
+	return func(c *Client) { c.onConnect = fn }
+}
+
+// WithOnDisconnect 连接断开时调用，连接属性仍可读取
+func WithOnDisconnect(fn func(conn ziface.IConnection)) ClientOption {
+	return func(c *Client) { c.onDisconnect = fn }
+}
+
+// WithOnDialError 拨号失败时调用，attempt 为连续失败次数（从 1 开始），wait 为下次重试前的等待时间
+func WithOnDialError(fn func(err error, attempt int, wait time.Duration)) ClientOption {
+	return func(c *Client) { c.onDialError = fn }
+}
+
+// NewClient 创建连接 network/addr 的客户端，Start 后开始拨号；network 为 tcp 系列或 unix，不支持 udp
+func NewClient(name, network, addr string, opts ...ClientOption) *Client {
+	c := &Client{
+		Name:        name,
+		Network:     network,
+		Addr:        addr,
+		dialTimeout: 5 * time.Second,
+		reconnect:   true,
+		connected:   make(chan struct{}),
+		quit:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.backoff.normalize()
+	c.core = NewServer(name, c.connOpts...).(*Server)
+	c.core.onConnStart = c.onConnect
+	c.core.onConnStop = c.connStopped
+	return c
+}
+
+// AddRouter 为服务端推送的 MsgID 注册处理函数，须在 Start 之前调用
+func (c *Client) AddRouter(msgID uint32, h ziface.MsgHandler) {
+	c.core.AddRouter(msgID, h)
+}
+
+// Start 启动工作池与心跳，并在后台拨号，不等待连接建立
+func (c *Client) Start() {
+	if c.started {
+		return
+	}
+	c.started = true
+	c.core.msgHandler.StartWorkerPool()
+	if c.core.heartbeat != nil {
+		c.core.heartbeat.start()
+	}
+	c.core.running.Store(true)
+	go c.run()
+}
+
+// run 拨号并等待连接断开，断开后按退避策略重连
+func (c *Client) run() {
+	defer close(c.done)
+	attempt := 0
+	for {
+		conn, err := c.dial()
+		if err != nil {
+			attempt++
+			wait := c.backoff.delay(attempt - 1)
+			fmt.Printf("[WARN]Client %s dial %s failed, err: %v, retry in %v\n", c.Name, c.Addr, err, wait)
+			if c.onDialError != nil {
+				c.onDialError(err, attempt, wait)
+			}
+			if !c.reconnect || !c.sleep(wait) {
+				return
+			}
+			continue
+		}
+		ctx := c.serve(conn)
+		up := time.Now()
+		select {
+		case <-ctx.Done():
+		case <-c.quit:
+			return
+		}
+		if !c.reconnect {
+			return
+		}
+		// 连接未保持到 Stable 即断开时继续累加退避，避免对端接受后立即关闭时形成重连风暴
+		if time.Since(up) >= c.backoff.Stable {
+			attempt = 0
+		}
+		attempt++
+		if !c.sleep(c.backoff.delay(attempt - 1)) {
+			return
+		}
+	}
+}
+
+func (c *Client) dial() (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.dialTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if c.tlsConfig != nil {
+		d := tls.Dialer{Config: c.tlsConfig}
+		return d.DialContext(ctx, c.Network, c.Addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, c.Network, c.Addr)
+}
+
+// sleep 等待 d，期间 Stop 则返回 false
+func (c *Client) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-c.quit:
+		return false
+	}
+}
+
+// serve 以服务端相同的连接实现启动读写协程，返回连接关闭时取消的上下文
+func (c *Client) serve(conn net.Conn) context.Context {
+	cc := NewConnection(c.core, conn, c.core.connID.Add(1))
+	c.core.connMgr.Add(cc)
+	c.mu.Lock()
+	c.conn = cc
+	close(c.connected)
+	c.mu.Unlock()
+	cc.Start()
+	return cc.Context()
+}
+
+// connStopped 连接关闭时清理当前连接并回调 OnDisconnect
+func (c *Client) connStopped(conn ziface.IConnection) {
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+		c.connected = make(chan struct{})
+	}
+	c.mu.Unlock()
+	if c.onDisconnect != nil {
+		c.onDisconnect(conn)
+	}
+}
+
+// Conn 返回当前连接，未连接时返回 nil
+func (c *Client) Conn() ziface.IConnection {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn
+}
+
+// WaitConnected 阻塞直到连接建立或 ctx 结束
+func (c *Client) WaitConnected(ctx context.Context) (ziface.IConnection, error) {
+	for {
+		c.mu.RLock()
+		conn, ch := c.conn, c.connected
+		c.mu.RUnlock()
+		if conn != nil {
+			return conn, nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, ErrNotConnected
+		}
+	}
+}
+
+// Send 经当前连接同步发送，未连接时返回 ErrNotConnected
+func (c *Client) Send(msgID uint32, data []byte) error {
+	conn := c.Conn()
+	if conn == nil {
+		return ErrNotConnected
+	}
+	return conn.Send(msgID, data)
+}
+
+// SendBuffered 经当前连接异步发送，未连接时返回 ErrNotConnected
+func (c *Client) SendBuffered(msgID uint32, data []byte) error {
+	conn := c.Conn()
+	if conn == nil {
+		return ErrNotConnected
+	}
+	return conn.SendBuffered(msgID, data)
+}
+
+// Stop 停止重连，等待在途消息处理完、发送队列写空后关闭连接；ctx 到期则强制关闭
+func (c *Client) Stop(ctx context.Context) error {
+	var err error
+	c.stopped.Do(func() {
+		close(c.quit)
+		if c.started {
+			<-c.done
+		}
+		err = c.core.Stop(ctx)
+	})
+	return err
+}
@@ -0,0 +1,365 @@
+package znet
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/zpool"
+)
+
+var (
+	ErrConnClosed    = errors.New("znet: connection closed")
+	ErrSendQueueFull = errors.New("znet: send queue full")
+)
+
+// OverflowPolicy 发送队列已满时 SendBuffered 的处理策略
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock 阻塞调用方直到队列有空位或连接关闭
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop 丢弃本条消息并返回 ErrSendQueueFull
+	OverflowDrop
+	// OverflowClose 视为慢消费者，关闭连接并返回 ErrSendQueueFull
+	OverflowClose
+)
+
+// outbound 发送队列中的一帧，done 非 nil 时写协程写完后回传结果
+type outbound struct {
+	buf  []byte
+	done chan error
+	// pooled 写出后将 buf 归还 zpool，广播等共享缓冲区不能归还
+	pooled bool
+}
+
+// Connection 服务端连接：读协程负责拆包分发，写协程独占 socket 写，
+// 所有发送都经由有界队列交给写协程，队列超过高水位时读协程暂停读取形成反压
+type Connection struct {
+	server *Server
+	conn   net.Conn
+	connID uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+
+	msgBuffChan chan outbound
+	// paused 读协程因发送队列高水位而暂停，写协程降到低水位后经 resume 唤醒
+	paused atomic.Bool
+	resume chan struct{}
+	// flushing 关闭后写协程写空队列即退出，writerDone 在写协程退出时关闭
+	flushing   chan struct{}
+	flushOnce  sync.Once
+	writerDone chan struct{}
+
+	activity
+	properties
+}
+
+func NewConnection(server *Server, conn net.Conn, connID uint64) *Connection {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Connection{
+		server:      server,
+		conn:        conn,
+		connID:      connID,
+		ctx:         ctx,
+		cancel:      cancel,
+		msgBuffChan: make(chan outbound, server.maxMsgChanLen),
+		resume:      make(chan struct{}, 1),
+		flushing:    make(chan struct{}),
+		writerDone:  make(chan struct{}),
+	}
+	c.touch()
+	return c
+}
+
+// Start 启动读写协程
+func (c *Connection) Start() {
+	c.server.readers.Add(1)
+	go c.startWriter()
+	go c.startReader()
+}
+
+// startReader 按 TLV 格式拆包，并将每条消息分发给 MsgID 对应的处理函数
+func (c *Connection) startReader() {
+	defer c.server.readers.Done()
+	defer func() {
+		// 优雅停止期间由 Server.Stop 负责写空队列后关闭
+		if !c.server.draining.Load() {
+			c.Close()
+		}
+	}()
+	if hb := c.server.heartbeat; hb != nil {
+		hb.watch(c)
+	}
+	if fn := c.server.onConnStart; fn != nil {
+		fn(c)
+	}
+	packet := c.server.packet
+	head := make([]byte, packet.GetHeadLen())
+	for {
+		if !c.waitWritable() {
+			return
+		}
+		if c.server.readTimeout > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(c.server.readTimeout))
+			// Stop 先置 draining 再打断读，这里设置超时后复查，避免覆盖 Stop 设置的截止时间
+			if c.server.draining.Load() {
+				return
+			}
+		}
+		if _, err := io.ReadFull(c.conn, head); err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) && !c.server.draining.Load() {
+				fmt.Printf("[ERROR]Conn %d read head failed, err: %v\n", c.connID, err)
+			}
+			return
+		}
+		msg, err := packet.Unpack(head)
+		if err != nil {
+			c.protocolError(malformed(err), nil)
+			return
+		}
+		// 先校验长度再分配，避免按对端声明的长度分配超大内存
+		if limit := c.server.maxPacketSize; limit > 0 && msg.GetDataLen() > limit {
+			if c.protocolError(oversize(msg, limit), msg) {
+				continue
+			}
+			return
+		}
+		req := &Request{conn: c, msg: msg, codec: c.server.codec}
+		if msg.GetDataLen() > 0 {
+			data := zpool.Get(int(msg.GetDataLen()))
+			msg.SetData(data)
+			req.pooled = true
+			if _, err := io.ReadFull(c.conn, data); err != nil {
+				req.release()
+				if errors.Is(err, io.ErrUnexpectedEOF) {
+					c.protocolError(malformed(err), msg)
+				} else if !errors.Is(err, net.ErrClosed) && !c.server.draining.Load() {
+					fmt.Printf("[ERROR]Conn %d read data failed, err: %v\n", c.connID, err)
+				}
+				return
+			}
+		}
+		c.touch()
+		// 心跳消息直接回复 pong，不进入消息路由
+		if hb := c.server.heartbeat; hb != nil && msg.GetMsgID() == hb.cfg.MsgID {
+			if !hb.cfg.SendPing {
+				_ = c.SendBuffered(hb.cfg.MsgID, nil)
+			}
+			req.release()
+			continue
+		}
+		if err := c.server.msgHandler.SendMsgToTaskQueue(req); err != nil {
+			fmt.Printf("[WARN]Conn %d msgID %d not handled, err: %v\n", c.connID, msg.GetMsgID(), err)
+			req.release()
+		}
+	}
+}
+
+// waitWritable 发送队列达到高水位时暂停读取，返回 false 表示连接已关闭
+func (c *Connection) waitWritable() bool {
+	for len(c.msgBuffChan) >= c.server.sendHighWater {
+		c.paused.Store(true)
+		// 置位后再检查一次，避免写协程在置位前已降到低水位而错过唤醒
+		if len(c.msgBuffChan) < c.server.sendHighWater {
+			c.paused.Store(false)
+			break
+		}
+		select {
+		case <-c.resume:
+		case <-c.ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// startWriter 消费发送队列，直到连接关闭
+func (c *Connection) startWriter() {
+	defer close(c.writerDone)
+	for {
+		select {
+		case out := <-c.msgBuffChan:
+			if err := c.write(out); err != nil {
+				fmt.Printf("[ERROR]Conn %d write failed, err: %v\n", c.connID, err)
+				c.Close()
+				return
+			}
+		case <-c.flushing:
+			for {
+				select {
+				case out := <-c.msgBuffChan:
+					if err := c.write(out); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// stopReading 打断阻塞中的读，读协程处理完当前消息后退出
+func (c *Connection) stopReading() { _ = c.conn.SetReadDeadline(time.Now()) }
+
+// flush 通知写协程写空发送队列，等待其退出后关闭连接
+func (c *Connection) flush() {
+	c.flushOnce.Do(func() { close(c.flushing) })
+	<-c.writerDone
+	c.Close()
+}
+
+func (c *Connection) write(out outbound) error {
+	if c.server.writeTimeout > 0 {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(c.server.writeTimeout))
+	}
+	_, err := c.conn.Write(out.buf)
+	if out.done != nil {
+		out.done <- err
+	}
+	if out.pooled {
+		zpool.Put(out.buf)
+	}
+	if len(c.msgBuffChan) <= c.server.sendLowWater && c.paused.CompareAndSwap(true, false) {
+		select {
+		case c.resume <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// Close 关闭 socket 并从连接管理器中移除
+func (c *Connection) Close() {
+	c.once.Do(func() {
+		c.cancel()
+		c.unwatch()
+		_ = c.conn.Close()
+		c.server.connMgr.Remove(c)
+		c.server.groupMgr.LeaveAll(c)
+		if fn := c.server.onConnStop; fn != nil {
+			fn(c)
+		}
+	})
+}
+
+func (c *Connection) GetConnID() uint64    { return c.connID }
+func (c *Connection) GetConn() net.Conn    { return c.conn }
+func (c *Connection) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// PeerCertificate 只返回通过校验的证书链中的叶子证书，未校验（如 InsecureSkipVerify）时返回 nil
+func (c *Connection) PeerCertificate() *x509.Certificate {
+	tc, ok := c.conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	chains := tc.ConnectionState().VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil
+	}
+	return chains[0][0]
+}
+func (c *Connection) Context() context.Context { return c.ctx }
+
+func (c *Connection) pack(msgID uint32, data []byte) ([]byte, error) {
+	return c.packMsg(NewMessage(msgID, data))
+}
+
+func (c *Connection) packMsg(msg ziface.IMessage) ([]byte, error) {
+	if c.ctx.Err() != nil {
+		return nil, ErrConnClosed
+	}
+	return c.server.packet.Pack(msg)
+}
+
+// Send 与 SendBuffered 共用发送队列以保证顺序，但始终阻塞等待入队并等待写完
+func (c *Connection) Send(msgID uint32, data []byte) error {
+	buf, err := c.pack(msgID, data)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	select {
+	case c.msgBuffChan <- outbound{buf: buf, done: done, pooled: c.server.packPooled}:
+	case <-c.ctx.Done():
+		return ErrConnClosed
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-c.ctx.Done():
+		return ErrConnClosed
+	}
+}
+
+// SendBuffered 入队即返回，队列满时按 OverflowPolicy 处理
+func (c *Connection) SendBuffered(msgID uint32, data []byte) error {
+	buf, err := c.pack(msgID, data)
+	if err != nil {
+		return err
+	}
+	return c.enqueue(buf)
+}
+
+// SendMsg 语义同 SendBuffered，用于自定义封包器下携带额外包头字段的消息
+func (c *Connection) SendMsg(msg ziface.IMessage) error {
+	buf, err := c.packMsg(msg)
+	if err != nil {
+		return err
+	}
+	return c.enqueue(buf)
+}
+
+// enqueue 将已封包的数据放入发送队列，队列满时按 OverflowPolicy 处理
+func (c *Connection) enqueue(buf []byte) error {
+	out := outbound{buf: buf, pooled: c.server.packPooled}
+	select {
+	case c.msgBuffChan <- out:
+		return nil
+	default:
+	}
+
+	switch c.server.overflowPolicy {
+	case OverflowDrop:
+		return ErrSendQueueFull
+	case OverflowClose:
+		fmt.Printf("[WARN]Conn %d send queue full, closing slow consumer\n", c.connID)
+		c.Close()
+		return ErrSendQueueFull
+	default:
+		select {
+		case c.msgBuffChan <- out:
+			return nil
+		case <-c.ctx.Done():
+			return ErrConnClosed
+		}
+	}
+}
+
+// trySendRaw 非阻塞投递已封包的数据，队列满或连接已关闭时返回 false
+func (c *Connection) trySendRaw(buf []byte) bool {
+	if c.ctx.Err() != nil {
+		return false
+	}
+	select {
+	case c.msgBuffChan <- outbound{buf: buf}:
+		return true
+	default:
+		return false
+	}
+}
+
+var _ ziface.IConnection = (*Connection)(nil)
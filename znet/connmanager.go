@@ -0,0 +1,82 @@
+package znet
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+var ErrConnNotFound = errors.New("znet: connection not found")
+
+// ConnManager 存活连接表
+type ConnManager struct {
+	mu    sync.RWMutex
+	conns map[uint64]ziface.IConnection
+}
+
+func NewConnManager() *ConnManager {
+	return &ConnManager{conns: make(map[uint64]ziface.IConnection)}
+}
+
+func (cm *ConnManager) Add(conn ziface.IConnection) {
+	cm.mu.Lock()
+	cm.conns[conn.GetConnID()] = conn
+	cm.mu.Unlock()
+}
+
+func (cm *ConnManager) Remove(conn ziface.IConnection) {
+	cm.mu.Lock()
+	delete(cm.conns, conn.GetConnID())
+	cm.mu.Unlock()
+}
+
+func (cm *ConnManager) Get(connID uint64) (ziface.IConnection, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	if conn, ok := cm.conns[connID]; ok {
+		return conn, nil
+	}
+	return nil, ErrConnNotFound
+}
+
+func (cm *ConnManager) Len() int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return len(cm.conns)
+}
+
+// Range 在锁外回调，fn 内可安全地关闭连接或再次访问管理器
+func (cm *ConnManager) Range(fn func(conn ziface.IConnection) bool) {
+	for _, conn := range cm.snapshot() {
+		if !fn(conn) {
+			return
+		}
+	}
+}
+
+func (cm *ConnManager) snapshot() []ziface.IConnection {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	conns := make([]ziface.IConnection, 0, len(cm.conns))
+	for _, conn := range cm.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// ClearConn 先在锁内取出快照再逐个关闭，Close 回调 Remove 时不会死锁
+func (cm *ConnManager) ClearConn() {
+	cm.mu.Lock()
+	conns := make([]ziface.IConnection, 0, len(cm.conns))
+	for id, conn := range cm.conns {
+		conns = append(conns, conn)
+		delete(cm.conns, id)
+	}
+	cm.mu.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+var _ ziface.IConnManager = (*ConnManager)(nil)
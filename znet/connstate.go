@@ -0,0 +1,105 @@
+package znet
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SparkleBo/zinx/ztimer"
+)
+
+// properties 连接属性表，嵌入到各连接实现中
+type properties struct {
+	mu sync.RWMutex
+	m  map[string]any
+}
+
+func (p *properties) SetProperty(key string, val any) {
+	p.mu.Lock()
+	if p.m == nil {
+		p.m = make(map[string]any)
+	}
+	p.m[key] = val
+	p.mu.Unlock()
+}
+
+func (p *properties) GetProperty(key string) (any, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	v, ok := p.m[key]
+	return v, ok
+}
+
+func (p *properties) RemoveProperty(key string) {
+	p.mu.Lock()
+	delete(p.m, key)
+	p.mu.Unlock()
+}
+
+// activity 最近一次收到数据的时间（UnixNano），以及心跳检测在时间轮上为连接注册的任务
+type activity struct {
+	last atomic.Int64
+
+	timerMu   sync.Mutex
+	idle      *ztimer.Timer
+	ping      *ztimer.Timer
+	unwatched bool // 连接已关闭，不再注册新任务
+}
+
+func (a *activity) touch() { a.last.Store(time.Now().UnixNano()) }
+
+func (a *activity) lastActivity() time.Time { return time.Unix(0, a.last.Load()) }
+
+// watchIdle 连接在 timeout 内没有收到任何数据时调用 onIdle；到期时若期间收到过数据，则按剩余时长重新计时
+func (a *activity) watchIdle(w *ztimer.Wheel, timeout time.Duration, onIdle func()) {
+	a.timerMu.Lock()
+	defer a.timerMu.Unlock()
+	if a.unwatched {
+		return
+	}
+	a.idle = w.AfterFunc(timeout, func() {
+		a.timerMu.Lock()
+		if a.unwatched {
+			a.timerMu.Unlock()
+			return
+		}
+		if idle := time.Since(a.lastActivity()); idle < timeout {
+			a.idle.Reset(timeout - idle)
+			a.timerMu.Unlock()
+			return
+		}
+		a.timerMu.Unlock()
+		onIdle()
+	})
+}
+
+// watchPing 每隔 interval 调用一次 fn
+func (a *activity) watchPing(w *ztimer.Wheel, interval time.Duration, fn func()) {
+	a.timerMu.Lock()
+	defer a.timerMu.Unlock()
+	if a.unwatched {
+		return
+	}
+	a.ping = w.Every(interval, fn)
+}
+
+// unwatch 连接关闭时取消全部任务
+func (a *activity) unwatch() {
+	a.timerMu.Lock()
+	defer a.timerMu.Unlock()
+	a.unwatched = true
+	if a.idle != nil {
+		a.idle.Stop()
+	}
+	if a.ping != nil {
+		a.ping.Stop()
+	}
+}
+
+// drainer 可参与 Server.Stop 优雅收尾的连接
+type drainer interface {
+	// stopReading 停止读取新消息
+	stopReading()
+	// flush 写空发送队列后关闭连接
+	flush()
+}
@@ -0,0 +1,46 @@
+package znet
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/zpool"
+)
+
+// defaultHeadLen 包头：MsgID(uint32) + DataLen(uint32)，小端序
+const defaultHeadLen = 8
+
+var errShortHead = errors.New("znet: packet head too short")
+
+// DataPack 默认的 TLV 封包/拆包实现
+//
+//	| MsgID 4B | DataLen 4B | Data DataLen B |
+type DataPack struct{}
+
+func NewDataPack() *DataPack { return &DataPack{} }
+
+func (dp *DataPack) GetHeadLen() uint32 { return defaultHeadLen }
+
+// Pack 将消息编码为包头 + 数据，返回的缓冲区来自 zpool，调用方独占时可在写出后归还
+func (dp *DataPack) Pack(msg ziface.IMessage) ([]byte, error) {
+	data := msg.GetData()
+	buf := zpool.Get(defaultHeadLen + len(data))
+	binary.LittleEndian.PutUint32(buf[0:4], msg.GetMsgID())
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(data)))
+	copy(buf[defaultHeadLen:], data)
+	return buf, nil
+}
+
+// Unpack 只解析包头，数据部分由读循环按 DataLen 继续读取
+func (dp *DataPack) Unpack(head []byte) (ziface.IMessage, error) {
+	if len(head) < defaultHeadLen {
+		return nil, errShortHead
+	}
+	return &Message{
+		ID:      binary.LittleEndian.Uint32(head[0:4]),
+		DataLen: binary.LittleEndian.Uint32(head[4:8]),
+	}, nil
+}
+
+var _ ziface.IDataPack = (*DataPack)(nil)
@@ -0,0 +1,721 @@
+//go:build linux
+
+package znet
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/zpool"
+)
+
+const (
+	epollReadEvents  = syscall.EPOLLIN | syscall.EPOLLRDHUP
+	epollWriteEvents = syscall.EPOLLOUT
+	epollWaitEvents  = 128
+	loopReadBufSize  = 64 << 10
+)
+
+// epollPoller 持有 M 个事件循环，新连接按连接 ID 取模固定分配到其中一个循环
+type epollPoller struct {
+	server *Server
+	loops  []*eventLoop
+}
+
+func newPoller(s *Server, loops int) (poller, error) {
+	p := &epollPoller{server: s}
+	for i := 0; i < loops; i++ {
+		l, err := newEventLoop()
+		if err != nil {
+			p.stop()
+			return nil, err
+		}
+		p.loops = append(p.loops, l)
+		go l.run()
+	}
+	return p, nil
+}
+
+func (p *epollPoller) add(conn net.Conn, connID uint64) (ziface.IConnection, error) {
+	remote := conn.RemoteAddr()
+	fd, err := detachFD(conn)
+	if err != nil {
+		return nil, err
+	}
+	loop := p.loops[connID%uint64(len(p.loops))]
+	c := newEpollConn(p.server, loop, fd, connID, remote)
+	p.server.connMgr.Add(c)
+	if hb := p.server.heartbeat; hb != nil {
+		hb.watch(c)
+	}
+	// OnConnStart 可能做鉴权等阻塞操作，不能占用 accept 循环或事件循环
+	if fn := p.server.onConnStart; fn != nil {
+		go func() {
+			fn(c)
+			c.Start()
+		}()
+		return c, nil
+	}
+	c.Start()
+	return c, nil
+}
+
+func (p *epollPoller) quiesce() {
+	for _, l := range p.loops {
+		l.quiesce()
+	}
+}
+
+func (p *epollPoller) stop() {
+	for _, l := range p.loops {
+		l.stop()
+	}
+}
+
+// detachFD 复制出连接的文件描述符并关闭原连接，使其脱离 Go runtime 的 netpoll
+func detachFD(conn net.Conn) (int, error) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return -1, fmt.Errorf("znet: %T does not expose a file descriptor", conn)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return -1, err
+	}
+	fd := -1
+	var dupErr error
+	if err := raw.Control(func(s uintptr) {
+		fd, dupErr = syscall.Dup(int(s))
+	}); err != nil {
+		return -1, err
+	}
+	_ = conn.Close()
+	if dupErr != nil {
+		return -1, dupErr
+	}
+	syscall.CloseOnExec(fd)
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		_ = syscall.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// eventLoop 单个 Reactor：一个 goroutine 串行处理所属连接的读写事件
+type eventLoop struct {
+	epfd int
+	// wake 管道用于唤醒阻塞在 EpollWait 上的循环，处理关闭请求、等待者或退出
+	wakeR, wakeW int
+
+	mu    sync.RWMutex
+	conns map[int]*epollConn
+	// closing 待关闭的 fd，waiters 等待当前这批事件处理完的调用方，均在每批事件之后处理；
+	// exited 表示循环已退出，此后的请求由调用方直接处理
+	closing []int
+	waiters []chan struct{}
+	exited  bool
+
+	buf      []byte
+	quit     atomic.Bool
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newEventLoop() (*eventLoop, error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		_ = syscall.Close(epfd)
+		return nil, err
+	}
+	ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(p[0])}
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, p[0], &ev); err != nil {
+		_ = syscall.Close(epfd)
+		_ = syscall.Close(p[0])
+		_ = syscall.Close(p[1])
+		return nil, err
+	}
+	return &eventLoop{
+		epfd:  epfd,
+		wakeR: p[0],
+		wakeW: p[1],
+		conns: make(map[int]*epollConn),
+		buf:   make([]byte, loopReadBufSize),
+		done:  make(chan struct{}),
+	}, nil
+}
+
+func (l *eventLoop) run() {
+	defer func() {
+		l.release(true)
+		close(l.done)
+	}()
+	events := make([]syscall.EpollEvent, epollWaitEvents)
+	for {
+		n, err := syscall.EpollWait(l.epfd, events, -1)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			fmt.Printf("[ERROR]EpollWait failed, err: %v\n", err)
+			return
+		}
+		for i := 0; i < n; i++ {
+			fd := int(events[i].Fd)
+			if fd == l.wakeR {
+				if l.drainWake() {
+					return
+				}
+				continue
+			}
+			l.mu.RLock()
+			c := l.conns[fd]
+			l.mu.RUnlock()
+			if c == nil {
+				continue
+			}
+			ev := events[i].Events
+			if ev&syscall.EPOLLOUT != 0 {
+				c.flushPending()
+			}
+			if ev&(syscall.EPOLLIN|syscall.EPOLLRDHUP|syscall.EPOLLHUP|syscall.EPOLLERR) != 0 {
+				// 挂断或出错时也走一次读，先把内核中剩余的数据读完，读到 EOF 或错误再关闭
+				c.handleRead(l.buf, ev&(syscall.EPOLLHUP|syscall.EPOLLERR) != 0)
+			}
+		}
+		l.release(false)
+	}
+}
+
+// drainWake 读空唤醒管道，返回 true 表示循环应退出
+func (l *eventLoop) drainWake() bool {
+	var b [64]byte
+	for {
+		if _, err := syscall.Read(l.wakeR, b[:]); err != nil {
+			break
+		}
+	}
+	return l.quit.Load()
+}
+
+// wake 调用方需持有 mu 且循环未退出，保证管道尚未被 stop 关闭
+func (l *eventLoop) wake() { _, _ = syscall.Write(l.wakeW, []byte{0}) }
+
+// release 关闭已注销连接的 fd 并通知等待者；final 为 true 时循环即将退出
+func (l *eventLoop) release(final bool) {
+	l.mu.Lock()
+	if final {
+		l.exited = true
+	}
+	fds, waiters := l.closing, l.waiters
+	l.closing, l.waiters = nil, nil
+	l.mu.Unlock()
+	for _, fd := range fds {
+		_ = syscall.Close(fd)
+	}
+	for _, w := range waiters {
+		close(w)
+	}
+}
+
+// closeFD fd 只由事件循环在一批事件处理完之后关闭：Close 可能来自任意 goroutine，
+// 若立即关闭，fd 号会被新接入的连接复用，本批剩余的事件与读操作就会落到新连接上
+func (l *eventLoop) closeFD(fd int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.exited {
+		_ = syscall.Close(fd)
+		return
+	}
+	l.closing = append(l.closing, fd)
+	l.wake()
+}
+
+// quiesce 等待循环处理完当前这批事件，返回后此前已取到的读事件均已分发完毕
+func (l *eventLoop) quiesce() {
+	done := make(chan struct{})
+	l.mu.Lock()
+	if l.exited {
+		l.mu.Unlock()
+		return
+	}
+	l.waiters = append(l.waiters, done)
+	l.wake()
+	l.mu.Unlock()
+	<-done
+}
+
+// register 调用方需持有 c.mu，保证注册前后事件掩码不会被并发修改
+func (l *eventLoop) register(c *epollConn) error {
+	l.mu.Lock()
+	l.conns[c.fd] = c
+	l.mu.Unlock()
+	ev := syscall.EpollEvent{Events: c.events, Fd: int32(c.fd)}
+	if err := syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_ADD, c.fd, &ev); err != nil {
+		l.remove(c)
+		return err
+	}
+	return nil
+}
+
+func (l *eventLoop) modify(c *epollConn, events uint32) error {
+	ev := syscall.EpollEvent{Events: events, Fd: int32(c.fd)}
+	return syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_MOD, c.fd, &ev)
+}
+
+func (l *eventLoop) remove(c *epollConn) {
+	l.mu.Lock()
+	if l.conns[c.fd] == c {
+		delete(l.conns, c.fd)
+	}
+	l.mu.Unlock()
+	_ = syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_DEL, c.fd, nil)
+}
+
+func (l *eventLoop) stop() {
+	l.stopOnce.Do(func() {
+		l.quit.Store(true)
+		l.mu.Lock()
+		if !l.exited {
+			l.wake()
+		}
+		l.mu.Unlock()
+		<-l.done
+		_ = syscall.Close(l.epfd)
+		_ = syscall.Close(l.wakeR)
+		_ = syscall.Close(l.wakeW)
+	})
+}
+
+// epollConn 事件循环模式下的连接，socket 为非阻塞 fd，读与拆包只在所属事件循环中进行
+type epollConn struct {
+	server *Server
+	loop   *eventLoop
+	fd     int
+	connID uint64
+	remote net.Addr
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+
+	// inbuf 未凑满一帧的残留数据，discard 为跳过超限帧时仍需丢弃的字节数，仅事件循环访问
+	inbuf   []byte
+	discard uint32
+
+	// mu 保护发送队列与 epoll 事件掩码，发送可能来自任意 goroutine
+	mu         sync.Mutex
+	pending    []outbound
+	events     uint32
+	registered bool
+	closed     bool
+	readPaused bool
+	stopped    bool
+	flushing   bool
+	flushed    chan struct{}
+
+	activity
+	properties
+}
+
+func newEpollConn(s *Server, loop *eventLoop, fd int, connID uint64, remote net.Addr) *epollConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &epollConn{
+		server:  s,
+		loop:    loop,
+		fd:      fd,
+		connID:  connID,
+		remote:  remote,
+		ctx:     ctx,
+		cancel:  cancel,
+		events:  epollReadEvents,
+		flushed: make(chan struct{}),
+	}
+	c.touch()
+	return c
+}
+
+// Start 将连接注册到事件循环，开始接收读事件
+func (c *epollConn) Start() {
+	c.mu.Lock()
+	if c.closed || c.registered {
+		c.mu.Unlock()
+		return
+	}
+	err := c.loop.register(c)
+	c.registered = err == nil
+	c.mu.Unlock()
+	if err != nil {
+		fmt.Printf("[ERROR]Conn %d epoll register failed, err: %v\n", c.connID, err)
+		c.Close()
+	}
+}
+
+// handleRead 每次读事件只读一次，水平触发下未读完的数据会再次触发，避免单连接饿死同循环的其它连接。
+// 已关闭或停止读取的连接不再读取：fd 由本循环关闭，检查之后到读完之前不会失效
+func (c *epollConn) handleRead(buf []byte, hangup bool) {
+	c.mu.Lock()
+	skip := c.closed || c.stopped
+	c.mu.Unlock()
+	if skip {
+		// 停止读取后仍会收到挂断与错误事件，此时连接已不可写，直接关闭
+		if hangup {
+			c.Close()
+		}
+		return
+	}
+	n, err := syscall.Read(c.fd, buf)
+	if err != nil {
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			return
+		}
+		c.Close()
+		return
+	}
+	if n == 0 {
+		c.Close()
+		return
+	}
+	data := buf[:n]
+	if c.discard > 0 {
+		skip := min(c.discard, uint32(len(data)))
+		c.discard -= skip
+		data = data[skip:]
+		if c.discard == 0 {
+			_ = c.SendBuffered(MsgIDError, []byte(ErrPacketTooLarge.Error()))
+		}
+	}
+	// 没有残留数据时直接在循环缓冲区上拆包，只拷贝不足一帧的尾部
+	if len(c.inbuf) == 0 {
+		rest := c.parse(data)
+		if len(rest) > 0 {
+			c.inbuf = append([]byte(nil), rest...)
+		}
+		return
+	}
+	c.inbuf = append(c.inbuf, data...)
+	rest := c.parse(c.inbuf)
+	if len(rest) == 0 {
+		c.inbuf = nil
+	} else {
+		c.inbuf = append(c.inbuf[:0], rest...)
+	}
+}
+
+// parse 拆出 data 中所有完整的帧并分发，返回剩余不足一帧的数据
+func (c *epollConn) parse(data []byte) []byte {
+	packet := c.server.packet
+	headLen := int(packet.GetHeadLen())
+	for c.discard == 0 && len(data) >= headLen && c.ctx.Err() == nil {
+		msg, err := packet.Unpack(data[:headLen])
+		if err != nil {
+			c.protocolError(malformed(err))
+			return nil
+		}
+		dataLen := msg.GetDataLen()
+		if limit := c.server.maxPacketSize; limit > 0 && dataLen > limit {
+			if !c.protocolError(oversize(msg, limit)) {
+				return nil
+			}
+			// 跳过本帧：缓冲区内的部分直接丢弃，其余由后续读事件继续丢弃
+			data = data[headLen:]
+			skip := min(dataLen, uint32(len(data)))
+			data = data[skip:]
+			c.discard = dataLen - skip
+			if c.discard == 0 {
+				_ = c.SendBuffered(MsgIDError, []byte(ErrPacketTooLarge.Error()))
+			}
+			continue
+		}
+		total := headLen + int(dataLen)
+		if len(data) < total {
+			break
+		}
+		body := data[headLen:total]
+		data = data[total:]
+		c.touch()
+		if hb := c.server.heartbeat; hb != nil && msg.GetMsgID() == hb.cfg.MsgID {
+			if !hb.cfg.SendPing {
+				_ = c.SendBuffered(hb.cfg.MsgID, nil)
+			}
+			continue
+		}
+		req := &Request{conn: c, msg: msg, codec: c.server.codec}
+		if dataLen > 0 {
+			// 处理函数可能异步执行，数据必须从共享缓冲区拷贝到池化缓冲区
+			buf := zpool.Get(len(body))
+			copy(buf, body)
+			msg.SetData(buf)
+			req.pooled = true
+		}
+		// 事件循环由多个连接共用，队列满时阻塞会拖住整个循环，且处理函数 Send 到同一循环上的连接时
+		// 等待的可写事件也无人处理，因此不能阻塞
+		if err := c.server.trySendToTaskQueue(req); err != nil {
+			fmt.Printf("[WARN]Conn %d msgID %d not handled, err: %v\n", c.connID, msg.GetMsgID(), err)
+			req.release()
+		}
+	}
+	return data
+}
+
+// protocolError 返回 true 表示跳过该帧继续处理
+func (c *epollConn) protocolError(err error) bool {
+	switch c.server.protocolError(c, err) {
+	case ProtocolErrorSkip:
+		return true
+	case ProtocolErrorReply:
+		_ = c.post(MsgIDError, []byte(err.Error()))
+	}
+	c.Close()
+	return false
+}
+
+// enqueue 队列为空时先尝试直接写 socket，写不完的部分进入发送队列并关注可写事件；
+// force 为 true 时忽略队列上限；out.pooled 为 true 时直接写完的 buf 归还 zpool；
+// out.done 非 nil 时在整帧写完后回传 nil
+func (c *epollConn) enqueue(out outbound, force bool) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrConnClosed
+	}
+	if len(c.pending) == 0 {
+		n, err := writeFD(c.fd, out.buf)
+		if err != nil {
+			c.mu.Unlock()
+			c.Close()
+			return err
+		}
+		if n == len(out.buf) {
+			c.mu.Unlock()
+			if out.pooled {
+				zpool.Put(out.buf)
+			}
+			if out.done != nil {
+				out.done <- nil
+			}
+			return nil
+		}
+		out.buf = out.buf[n:]
+	}
+	if !force && len(c.pending) >= c.server.maxMsgChanLen {
+		c.mu.Unlock()
+		if c.server.overflowPolicy == OverflowClose {
+			fmt.Printf("[WARN]Conn %d send queue full, closing slow consumer\n", c.connID)
+			c.Close()
+		}
+		return ErrSendQueueFull
+	}
+	c.pending = append(c.pending, out)
+	if len(c.pending) >= c.server.sendHighWater {
+		c.readPaused = true
+	}
+	c.updateEvents()
+	c.mu.Unlock()
+	return nil
+}
+
+// flushPending 可写事件到来时继续写发送队列
+func (c *epollConn) flushPending() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	for len(c.pending) > 0 {
+		out := &c.pending[0]
+		n, err := writeFD(c.fd, out.buf)
+		if err != nil {
+			c.mu.Unlock()
+			c.Close()
+			return
+		}
+		if n < len(out.buf) {
+			out.buf = out.buf[n:]
+			break
+		}
+		if out.done != nil {
+			out.done <- nil
+		}
+		c.pending[0] = outbound{}
+		c.pending = c.pending[1:]
+	}
+	if c.readPaused && len(c.pending) <= c.server.sendLowWater {
+		c.readPaused = false
+	}
+	if len(c.pending) == 0 {
+		c.pending = nil
+		if c.flushing {
+			c.flushing = false
+			close(c.flushed)
+		}
+	}
+	c.updateEvents()
+	c.mu.Unlock()
+}
+
+// updateEvents 按当前状态重新计算关注的事件，调用方需持有 mu
+func (c *epollConn) updateEvents() {
+	var want uint32
+	if !c.stopped && !c.readPaused {
+		want |= epollReadEvents
+	}
+	if len(c.pending) > 0 {
+		want |= epollWriteEvents
+	}
+	if want == c.events {
+		return
+	}
+	c.events = want
+	// 尚未注册时只记录掩码，注册时按最新掩码添加
+	if c.registered {
+		_ = c.loop.modify(c, want)
+	}
+}
+
+// writeFD 非阻塞写，返回已写入的字节数；内核缓冲区满时返回 nil 错误
+func writeFD(fd int, buf []byte) (int, error) {
+	written := 0
+	for written < len(buf) {
+		n, err := syscall.Write(fd, buf[written:])
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			if err == syscall.EAGAIN {
+				return written, nil
+			}
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+func (c *epollConn) stopReading() {
+	c.mu.Lock()
+	c.stopped = true
+	c.updateEvents()
+	c.mu.Unlock()
+}
+
+func (c *epollConn) flush() {
+	c.mu.Lock()
+	wait := len(c.pending) > 0 && !c.closed
+	if wait {
+		c.flushing = true
+	}
+	c.mu.Unlock()
+	if wait {
+		select {
+		case <-c.flushed:
+		case <-c.ctx.Done():
+		}
+	}
+	c.Close()
+}
+
+// Close 从事件循环注销，fd 交给所属事件循环关闭；等待中的 Send 经 Context 得到 ErrConnClosed
+func (c *epollConn) Close() {
+	c.once.Do(func() {
+		c.cancel()
+		c.unwatch()
+		c.mu.Lock()
+		c.closed = true
+		c.registered = false
+		c.pending = nil
+		c.mu.Unlock()
+		c.loop.remove(c)
+		c.loop.closeFD(c.fd)
+		c.server.connMgr.Remove(c)
+		c.server.groupMgr.LeaveAll(c)
+		if fn := c.server.onConnStop; fn != nil {
+			fn(c)
+		}
+	})
+}
+
+func (c *epollConn) GetConnID() uint64                  { return c.connID }
+func (c *epollConn) GetConn() net.Conn                  { return nil }
+func (c *epollConn) RemoteAddr() net.Addr               { return c.remote }
+func (c *epollConn) PeerCertificate() *x509.Certificate { return nil }
+func (c *epollConn) Context() context.Context           { return c.ctx }
+
+func (c *epollConn) pack(msgID uint32, data []byte) ([]byte, error) {
+	return c.packMsg(NewMessage(msgID, data))
+}
+
+func (c *epollConn) packMsg(msg ziface.IMessage) ([]byte, error) {
+	if c.ctx.Err() != nil {
+		return nil, ErrConnClosed
+	}
+	return c.server.packet.Pack(msg)
+}
+
+// Send 不受队列上限约束，等待数据写入 socket 后返回。未启用工作池时处理函数运行在事件循环中，
+// 等待会卡住负责写出的循环自身，此时与 post 相同，入队即返回
+func (c *epollConn) Send(msgID uint32, data []byte) error {
+	if c.server.workerPoolSize <= 0 {
+		return c.post(msgID, data)
+	}
+	buf, err := c.pack(msgID, data)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	if err := c.enqueue(outbound{buf: buf, done: done, pooled: c.server.packPooled}, true); err != nil {
+		return err
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-c.ctx.Done():
+		return ErrConnClosed
+	}
+}
+
+// post 不受队列上限约束且不等待写出，供事件循环内的回复使用
+func (c *epollConn) post(msgID uint32, data []byte) error {
+	buf, err := c.pack(msgID, data)
+	if err != nil {
+		return err
+	}
+	return c.enqueue(outbound{buf: buf, pooled: c.server.packPooled}, true)
+}
+
+// SendBuffered 队列满时 OverflowBlock 按 OverflowDrop 处理，见 TransportEpoll
+func (c *epollConn) SendBuffered(msgID uint32, data []byte) error {
+	buf, err := c.pack(msgID, data)
+	if err != nil {
+		return err
+	}
+	return c.enqueue(outbound{buf: buf, pooled: c.server.packPooled}, false)
+}
+
+// SendMsg 语义同 SendBuffered，用于自定义封包器下携带额外包头字段的消息
+func (c *epollConn) SendMsg(msg ziface.IMessage) error {
+	buf, err := c.packMsg(msg)
+	if err != nil {
+		return err
+	}
+	return c.enqueue(outbound{buf: buf, pooled: c.server.packPooled}, false)
+}
+
+func (c *epollConn) trySendRaw(buf []byte) bool {
+	return c.enqueue(outbound{buf: buf}, false) == nil
+}
+
+var (
+	_ ziface.IConnection = (*epollConn)(nil)
+	_ drainer            = (*epollConn)(nil)
+	_ rawSender          = (*epollConn)(nil)
+)
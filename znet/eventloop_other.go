@@ -0,0 +1,9 @@
+//go:build !linux
+
+package znet
+
+import "errors"
+
+func newPoller(s *Server, loops int) (poller, error) {
+	return nil, errors.New("znet: epoll transport is only supported on linux")
+}
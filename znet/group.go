@@ -0,0 +1,127 @@
+package znet
+
+import (
+	"sync"
+
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+// GroupManager 连接分组管理，组内成员为空时自动删除该组
+type GroupManager struct {
+	packet ziface.IDataPack
+
+	mu     sync.RWMutex
+	groups map[string]map[uint64]ziface.IConnection
+	// joined 反向索引：连接 ID -> 所在组，用于连接关闭时快速清理
+	joined map[uint64]map[string]struct{}
+}
+
+func NewGroupManager(packet ziface.IDataPack) *GroupManager {
+	return &GroupManager{
+		packet: packet,
+		groups: make(map[string]map[uint64]ziface.IConnection),
+		joined: make(map[uint64]map[string]struct{}),
+	}
+}
+
+// Join 已关闭的连接返回 ErrConnClosed。连接关闭时先取消 Context 再调用 LeaveAll，
+// 在写锁内检查可保证晚到的 Join 不会把已清理的连接重新加入
+func (gm *GroupManager) Join(group string, conn ziface.IConnection) error {
+	id := conn.GetConnID()
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	if conn.Context().Err() != nil {
+		return ErrConnClosed
+	}
+	members, ok := gm.groups[group]
+	if !ok {
+		members = make(map[uint64]ziface.IConnection)
+		gm.groups[group] = members
+	}
+	members[id] = conn
+	if gm.joined[id] == nil {
+		gm.joined[id] = make(map[string]struct{})
+	}
+	gm.joined[id][group] = struct{}{}
+	return nil
+}
+
+func (gm *GroupManager) Leave(group string, conn ziface.IConnection) {
+	gm.mu.Lock()
+	gm.leave(group, conn.GetConnID())
+	gm.mu.Unlock()
+}
+
+func (gm *GroupManager) LeaveAll(conn ziface.IConnection) {
+	id := conn.GetConnID()
+	gm.mu.Lock()
+	for group := range gm.joined[id] {
+		gm.leave(group, id)
+	}
+	gm.mu.Unlock()
+}
+
+// leave 调用方需持有写锁
+func (gm *GroupManager) leave(group string, id uint64) {
+	if members, ok := gm.groups[group]; ok {
+		delete(members, id)
+		if len(members) == 0 {
+			delete(gm.groups, group)
+		}
+	}
+	if groups, ok := gm.joined[id]; ok {
+		delete(groups, group)
+		if len(groups) == 0 {
+			delete(gm.joined, id)
+		}
+	}
+}
+
+func (gm *GroupManager) Members(group string) []ziface.IConnection {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	members := make([]ziface.IConnection, 0, len(gm.groups[group]))
+	for _, conn := range gm.groups[group] {
+		members = append(members, conn)
+	}
+	return members
+}
+
+func (gm *GroupManager) Groups() []string {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	names := make([]string, 0, len(gm.groups))
+	for name := range gm.groups {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (gm *GroupManager) Broadcast(group string, msgID uint32, data []byte) int {
+	return broadcast(gm.packet, gm.Members(group), msgID, data)
+}
+
+// rawSender 支持直接投递已封包数据的连接，广播时只封包一次
+type rawSender interface {
+	trySendRaw(buf []byte) bool
+}
+
+// broadcast 只封包一次，对每个连接做非阻塞入队；未实现 rawSender 的连接无法保证不阻塞，直接跳过
+func broadcast(packet ziface.IDataPack, conns []ziface.IConnection, msgID uint32, data []byte) int {
+	if len(conns) == 0 {
+		return 0
+	}
+	buf, err := packet.Pack(NewMessage(msgID, data))
+	if err != nil {
+		return 0
+	}
+	sent := 0
+	for _, conn := range conns {
+		if rs, ok := conn.(rawSender); ok && rs.trySendRaw(buf) {
+			sent++
+		}
+	}
+	return sent
+}
+
+var _ ziface.IGroupManager = (*GroupManager)(nil)
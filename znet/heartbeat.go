@@ -0,0 +1,100 @@
+package znet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/ztimer"
+)
+
+// MsgIDHeartbeat 系统保留的默认心跳 MsgID
+const MsgIDHeartbeat uint32 = 0xFFFFFFFE
+
+const defaultHeartbeatInterval = 10 * time.Second
+
+// HeartbeatConfig 心跳与空闲检测配置
+type HeartbeatConfig struct {
+	// Interval 主动发送 ping 的周期，同时决定时间轮精度，默认 10s
+	Interval time.Duration
+	// Timeout 连接在该时长内没有收到任何数据即视为失活，默认 3 * Interval
+	Timeout time.Duration
+	// MsgID 心跳消息 ID，默认 MsgIDHeartbeat；收到该 ID 的消息直接回复同 ID 的 pong，不进入消息路由
+	MsgID uint32
+	// SendPing 为 true 时主动向对端发送 ping，否则只等待对端发送
+	SendPing bool
+	// OnIdle 失活连接被关闭前回调
+	OnIdle func(conn ziface.IConnection)
+}
+
+func (cfg *HeartbeatConfig) normalize() {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultHeartbeatInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 3 * cfg.Interval
+	}
+	if cfg.MsgID == 0 {
+		cfg.MsgID = MsgIDHeartbeat
+	}
+}
+
+// heartbeatChecker 基于时间轮为每个连接注册空闲检测与定时 ping，插入与取消均为 O(1)
+type heartbeatChecker struct {
+	cfg   HeartbeatConfig
+	ping  []byte // 预先封好的 ping 帧
+	wheel *ztimer.Wheel
+}
+
+func newHeartbeatChecker(cfg HeartbeatConfig, packet ziface.IDataPack) *heartbeatChecker {
+	cfg.normalize()
+	hc := &heartbeatChecker{cfg: cfg}
+	if cfg.SendPing {
+		buf, err := packet.Pack(NewMessage(cfg.MsgID, nil))
+		if err != nil {
+			fmt.Printf("[ERROR]Pack heartbeat ping failed, err: %v\n", err)
+		}
+		hc.ping = buf
+	}
+	return hc
+}
+
+// tick 时间轮精度取检测周期与超时中较小者的 1/10，不低于 1ms
+func (hc *heartbeatChecker) tick() time.Duration {
+	d := min(hc.cfg.Interval, hc.cfg.Timeout) / 10
+	return max(d, time.Millisecond)
+}
+
+func (hc *heartbeatChecker) start() {
+	hc.wheel = ztimer.New(hc.tick())
+}
+
+func (hc *heartbeatChecker) stop() {
+	if hc.wheel != nil {
+		hc.wheel.Stop()
+	}
+}
+
+// watched 可由心跳检测管理的连接，由嵌入的 activity 实现
+type watched interface {
+	watchIdle(w *ztimer.Wheel, timeout time.Duration, onIdle func())
+	watchPing(w *ztimer.Wheel, interval time.Duration, fn func())
+}
+
+// watch 连接建立时注册，回调在时间轮 goroutine 中执行，ping 以非阻塞方式入队避免拖慢时间轮
+func (hc *heartbeatChecker) watch(conn ziface.IConnection) {
+	c, ok := conn.(watched)
+	if !ok || hc.wheel == nil {
+		return
+	}
+	c.watchIdle(hc.wheel, hc.cfg.Timeout, func() {
+		fmt.Printf("[WARN]Conn %d idle timeout, remote: %s\n", conn.GetConnID(), conn.RemoteAddr())
+		if hc.cfg.OnIdle != nil {
+			hc.cfg.OnIdle(conn)
+		}
+		conn.Close()
+	})
+	if rs, ok := conn.(rawSender); ok && hc.ping != nil {
+		c.watchPing(hc.wheel, hc.cfg.Interval, func() { rs.trySendRaw(hc.ping) })
+	}
+}
@@ -0,0 +1,25 @@
+package znet
+
+import "github.com/SparkleBo/zinx/ziface"
+
+// Message TLV 消息的默认实现
+type Message struct {
+	ID      uint32
+	DataLen uint32
+	Data    []byte
+}
+
+// NewMessage 以 MsgID 与数据构造消息，DataLen 自动取数据长度
+func NewMessage(id uint32, data []byte) *Message {
+	return &Message{ID: id, DataLen: uint32(len(data)), Data: data}
+}
+
+func (m *Message) GetMsgID() uint32   { return m.ID }
+func (m *Message) GetDataLen() uint32 { return m.DataLen }
+func (m *Message) GetData() []byte    { return m.Data }
+
+func (m *Message) SetMsgID(id uint32)  { m.ID = id }
+func (m *Message) SetDataLen(n uint32) { m.DataLen = n }
+func (m *Message) SetData(data []byte) { m.Data = data }
+
+var _ ziface.IMessage = (*Message)(nil)
@@ -0,0 +1,184 @@
+package znet
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+// OverloadPolicy 工作队列已满时的处理策略
+type OverloadPolicy uint8
+
+const (
+	// PolicyBlock 阻塞读协程直到队列有空位（对该连接形成反压）；
+	// epoll 事件循环与 UDP 读循环由多个连接共用，不能阻塞，按 PolicyDrop 处理
+	PolicyBlock OverloadPolicy = iota
+	// PolicyDrop 直接丢弃消息
+	PolicyDrop
+	// PolicyReject 丢弃消息并向客户端回复 MsgIDError 错误帧
+	PolicyReject
+)
+
+// MsgIDError 系统保留的错误帧 MsgID，数据为错误描述文本
+const MsgIDError uint32 = 0xFFFFFFFF
+
+var ErrWorkerBusy = errors.New("znet: worker queue full")
+
+// MsgHandle 基于 MsgID 的消息路由，附带按连接 ID 哈希的有界工作池
+type MsgHandle struct {
+	mu   sync.RWMutex
+	apis map[uint32]ziface.MsgHandler
+
+	workerPoolSize int
+	maxTaskLen     int
+	policy         OverloadPolicy
+	taskQueue      []chan ziface.IRequest
+	quit           chan struct{}
+	wg             sync.WaitGroup
+}
+
+// NewMsgHandle workerPoolSize <= 0 时不启用工作池，消息在读协程中同步处理
+func NewMsgHandle(workerPoolSize, maxTaskLen int, policy OverloadPolicy) *MsgHandle {
+	if maxTaskLen <= 0 {
+		maxTaskLen = defaultMaxWorkerTaskLen
+	}
+	return &MsgHandle{
+		apis:           make(map[uint32]ziface.MsgHandler),
+		workerPoolSize: workerPoolSize,
+		maxTaskLen:     maxTaskLen,
+		policy:         policy,
+	}
+}
+
+// AddRouter 注册处理函数，同一 MsgID 重复注册视为编程错误
+func (mh *MsgHandle) AddRouter(msgID uint32, h ziface.MsgHandler) {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	if _, ok := mh.apis[msgID]; ok {
+		panic(fmt.Sprintf("znet: handler for msgID %d already registered", msgID))
+	}
+	mh.apis[msgID] = h
+}
+
+// DoMsgHandler 查找并执行 MsgID 对应的处理函数
+func (mh *MsgHandle) DoMsgHandler(req ziface.IRequest) {
+	if r, ok := req.(*Request); ok {
+		defer r.release()
+	}
+	mh.mu.RLock()
+	h, ok := mh.apis[req.GetMsgID()]
+	mh.mu.RUnlock()
+	if !ok {
+		fmt.Printf("[WARN]no handler for msgID %d\n", req.GetMsgID())
+		return
+	}
+	if err := h(req); err != nil {
+		fmt.Printf("[ERROR]handle msgID %d failed, err: %v\n", req.GetMsgID(), err)
+	}
+}
+
+// StartWorkerPool 每个 worker 独占一条任务队列
+func (mh *MsgHandle) StartWorkerPool() {
+	if mh.workerPoolSize <= 0 || mh.taskQueue != nil {
+		return
+	}
+	mh.quit = make(chan struct{})
+	mh.taskQueue = make([]chan ziface.IRequest, mh.workerPoolSize)
+	for i := range mh.taskQueue {
+		mh.taskQueue[i] = make(chan ziface.IRequest, mh.maxTaskLen)
+		mh.wg.Add(1)
+		go mh.startOneWorker(mh.taskQueue[i])
+	}
+}
+
+func (mh *MsgHandle) startOneWorker(queue chan ziface.IRequest) {
+	defer mh.wg.Done()
+	for {
+		select {
+		case req := <-queue:
+			mh.DoMsgHandler(req)
+		case <-mh.quit:
+			// 退出前执行完已入队的任务
+			for {
+				select {
+				case req := <-queue:
+					mh.DoMsgHandler(req)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (mh *MsgHandle) StopWorkerPool() {
+	if mh.taskQueue == nil {
+		return
+	}
+	close(mh.quit)
+	mh.wg.Wait()
+	mh.taskQueue = nil
+}
+
+// SendMsgToTaskQueue 按连接 ID 取模选择 worker，保证同一连接内的消息顺序
+func (mh *MsgHandle) SendMsgToTaskQueue(req ziface.IRequest) error {
+	return mh.sendToTaskQueue(req, true)
+}
+
+// trySendMsgToTaskQueue 队列满时 PolicyBlock 按 PolicyDrop 处理，供多个对端共用的读循环使用
+func (mh *MsgHandle) trySendMsgToTaskQueue(req ziface.IRequest) error {
+	return mh.sendToTaskQueue(req, false)
+}
+
+// nonBlockingQueue 可非阻塞投递的工作池，MsgHandle 实现该接口
+type nonBlockingQueue interface {
+	trySendMsgToTaskQueue(req ziface.IRequest) error
+}
+
+// trySendToTaskQueue 供多个连接共用的读循环（epoll 事件循环、UDP 读循环）投递请求，
+// PolicyBlock 按 PolicyDrop 处理；自定义 IMsgHandle 未实现 nonBlockingQueue 时仍按其自身策略投递
+func (s *Server) trySendToTaskQueue(req ziface.IRequest) error {
+	if q, ok := s.msgHandler.(nonBlockingQueue); ok {
+		return q.trySendMsgToTaskQueue(req)
+	}
+	return s.msgHandler.SendMsgToTaskQueue(req)
+}
+
+func (mh *MsgHandle) sendToTaskQueue(req ziface.IRequest, block bool) error {
+	if mh.taskQueue == nil {
+		mh.DoMsgHandler(req)
+		return nil
+	}
+	conn := req.GetConnection()
+	queue := mh.taskQueue[conn.GetConnID()%uint64(len(mh.taskQueue))]
+	select {
+	case queue <- req:
+		return nil
+	default:
+	}
+
+	policy := mh.policy
+	if !block && policy == PolicyBlock {
+		policy = PolicyDrop
+	}
+	switch policy {
+	case PolicyDrop:
+		return ErrWorkerBusy
+	case PolicyReject:
+		_ = conn.SendBuffered(MsgIDError, []byte(ErrWorkerBusy.Error()))
+		return ErrWorkerBusy
+	default:
+		select {
+		case queue <- req:
+			return nil
+		case <-conn.Context().Done():
+			return ErrConnClosed
+		case <-mh.quit:
+			return ErrWorkerBusy
+		}
+	}
+}
+
+var _ ziface.IMsgHandle = (*MsgHandle)(nil)
@@ -0,0 +1,87 @@
+package znet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// 监听的网络类型
+const (
+	// NetworkTCP TCP 监听（默认），IP 族由 Server.IPVersion 决定
+	NetworkTCP = "tcp"
+	// NetworkUnix unix 域 socket，适合同机 sidecar 通信
+	NetworkUnix = "unix"
+	// NetworkUDP UDP 数据报：按对端地址划分伪会话，数据报交给同一套消息路由处理
+	NetworkUDP = "udp"
+)
+
+// staleDialTimeout 探测已存在的 socket 文件是否仍有进程监听的超时时间
+const staleDialTimeout = 100 * time.Millisecond
+
+// ParseNetwork 校验配置中的网络类型，空字符串表示 NetworkTCP
+func ParseNetwork(name string) (string, error) {
+	switch name {
+	case "", NetworkTCP:
+		return NetworkTCP, nil
+	case NetworkUnix, NetworkUDP:
+		return name, nil
+	default:
+		return "", fmt.Errorf("znet: unknown network %q", name)
+	}
+}
+
+// listenUnix 清理残留的 socket 文件后监听 path，perm 非 0 时修改 socket 文件权限
+func listenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("znet: unix socket path is empty")
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	l, err := net.ListenUnix(NetworkUnix, &net.UnixAddr{Name: path, Net: NetworkUnix})
+	if err != nil {
+		return nil, err
+	}
+	if perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// removeStaleSocket 删除上次进程异常退出遗留的 socket 文件；
+// 仍有进程在监听或 path 不是 socket 文件时返回错误，不会误删
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("znet: %s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout(NetworkUnix, path, staleDialTimeout); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("znet: unix socket %s is in use", path)
+	}
+	fmt.Printf("[WARN]Removing stale unix socket %s\n", path)
+	return os.Remove(path)
+}
+
+// listenUDP 按 IPVersion 对应的 UDP 网络监听数据报
+func (s *Server) listenUDP() (*net.UDPConn, error) {
+	network := strings.Replace(s.IPVersion, "tcp", "udp", 1)
+	addr, err := net.ResolveUDPAddr(network, fmt.Sprintf("%s:%d", s.IP, s.Port))
+	if err != nil {
+		return nil, err
+	}
+	return net.ListenUDP(network, addr)
+}
@@ -0,0 +1,223 @@
+package znet
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/SparkleBo/zinx/internal/graceful"
+	"github.com/SparkleBo/zinx/internal/prefork"
+	"github.com/SparkleBo/zinx/zcodec"
+	"github.com/SparkleBo/zinx/zconfig"
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+const (
+	defaultMaxConn          = 12000
+	defaultMaxMsgChanLen    = 1024
+	defaultMaxWorkerTaskLen = 1024
+	defaultMaxPacketSize    = 4096
+	defaultStopTimeout      = 5 * time.Second
+	defaultHandshakeTimeout = 10 * time.Second
+)
+
+var defaultWorkerPoolSize = runtime.NumCPU()
+
+// Option 构造 Server 时的可选配置
+type Option func(s *Server)
+
+// WithPacket 替换默认的封包/拆包实现
+func WithPacket(dp ziface.IDataPack) Option {
+	return func(s *Server) { s.packet = dp }
+}
+
+// WithMaxConn 设置最大连接数，<= 0 表示不限制
+func WithMaxConn(n int) Option {
+	return func(s *Server) { s.maxConn = n }
+}
+
+// WithMaxMsgChanLen 设置每条连接的发送缓冲队列长度
+func WithMaxMsgChanLen(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxMsgChanLen = n
+		}
+	}
+}
+
+// WithSendHighWater 设置发送队列高水位，达到后暂停读取该连接，降到一半时恢复；
+// 默认等于发送队列长度
+func WithSendHighWater(n int) Option {
+	return func(s *Server) { s.sendHighWater = n }
+}
+
+// WithOverflowPolicy 设置发送队列满时 SendBuffered 的处理策略
+func WithOverflowPolicy(p OverflowPolicy) Option {
+	return func(s *Server) { s.overflowPolicy = p }
+}
+
+// WithWorkerPool 设置工作池 worker 数量与每个 worker 的任务队列长度，
+// size <= 0 表示不启用工作池，消息在连接读协程中同步处理
+func WithWorkerPool(size, maxTaskLen int) Option {
+	return func(s *Server) {
+		s.workerPoolSize = size
+		s.maxWorkerTaskLen = maxTaskLen
+	}
+}
+
+// WithOverloadPolicy 设置工作队列满时的处理策略
+func WithOverloadPolicy(p OverloadPolicy) Option {
+	return func(s *Server) { s.overloadPolicy = p }
+}
+
+// WithHeartbeat 启用心跳与空闲检测
+func WithHeartbeat(cfg HeartbeatConfig) Option {
+	return func(s *Server) { s.heartbeatCfg = &cfg }
+}
+
+// WithMaxPacketSize 设置单条消息数据部分的最大字节数，0 表示不限制
+func WithMaxPacketSize(n uint32) Option {
+	return func(s *Server) { s.maxPacketSize = n }
+}
+
+// WithProtocolErrorAction 设置收到超限或畸形帧时的处理方式，默认直接关闭连接
+func WithProtocolErrorAction(a ProtocolErrorAction) Option {
+	return func(s *Server) { s.protocolErrorAction = a }
+}
+
+// WithTimeouts 设置读写超时：read 为等待下一帧的最长时间，write 为单次写 socket 的最长时间，0 表示不限制
+func WithTimeouts(read, write time.Duration) Option {
+	return func(s *Server) {
+		s.readTimeout = read
+		s.writeTimeout = write
+	}
+}
+
+// WithReusePort 以 SO_REUSEPORT 创建 n 个绑定同一端口的监听，每个监听独立 accept，缓解单监听的 accept 竞争
+func WithReusePort(n int) Option {
+	return func(s *Server) { s.reusePort = n }
+}
+
+// WithPrefork 启用 prefork 模式：Start 在父进程中派生 children 个子进程（<= 0 时取 CPU 数）并监管，
+// 崩溃的子进程会被重新拉起；子进程重新执行当前程序，以 SO_REUSEPORT 监听同一端口独立 accept
+func WithPrefork(children int) Option {
+	return func(s *Server) { s.master = &prefork.Master{Children: children} }
+}
+
+// WithGracefulRestart 启用不停机重启：收到 SIGUSR2 时拉起新进程并传递监听 fd，
+// 新进程就绪后当前进程停止 accept、处理完在途消息再退出
+func WithGracefulRestart() Option {
+	return func(s *Server) { s.restarter = &graceful.Restarter{} }
+}
+
+// WithCodec 设置消息数据的编解码器，默认 zcodec.JSON
+func WithCodec(c zcodec.Codec) Option {
+	return func(s *Server) { s.codec = c }
+}
+
+// WithUnixSocket 改为监听 unix 域 socket：启动时清理上次遗留的 socket 文件（仍有进程监听时启动失败），
+// perm 非 0 时设置 socket 文件权限；停止时删除 socket 文件。不支持 SO_REUSEPORT 与 prefork
+func WithUnixSocket(path string, perm os.FileMode) Option {
+	return func(s *Server) {
+		s.network = NetworkUnix
+		s.socketPath = path
+		s.socketPerm = perm
+	}
+}
+
+// WithUDP 改为在 IP:Port 上收发 UDP 数据报：每个数据报包含一个或多个完整帧，同一对端地址的数据报
+// 归入同一伪会话并经同一套消息路由处理；会话空闲 sessionTimeout（<= 0 时为 1 分钟）后关闭，
+// 启用心跳时改由心跳超时决定。发送直接写数据报，不经过发送队列；OnAccept 不会被调用。
+//
+// 全部会话共用一个读循环，因此必须启用工作池，队列满时 PolicyBlock 按 PolicyDrop 处理；
+// OnConnStart 在独立 goroutine 中执行，返回前该对端后续的数据报被丢弃。UDP 源地址可以伪造：
+// 新对端的首个数据报须以完整合法的帧开头才会创建会话，会话总数受 WithMaxConn 约束，
+// 面向公网时应在 OnConnStart 或首条消息中鉴权，并视情况调小 maxConn 与 sessionTimeout
+func WithUDP(sessionTimeout time.Duration) Option {
+	return func(s *Server) {
+		s.network = NetworkUDP
+		if sessionTimeout > 0 {
+			s.udpTimeout = sessionTimeout
+		}
+	}
+}
+
+// WithTLS 启用 TLS，cfg 至少包含服务端证书；设置 ClientCAs 与 ClientAuth 即为双向认证，
+// 握手通过后可经 IConnection.PeerCertificate 取得客户端证书。TLS 下 epoll 传输回退为 goroutine 模型
+func WithTLS(cfg *tls.Config) Option {
+	return func(s *Server) { s.tlsConfig = cfg }
+}
+
+// WithTLSHandshakeTimeout 设置 TLS 握手超时，默认 10s
+func WithTLSHandshakeTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		if d > 0 {
+			s.handshakeTimeout = d
+		}
+	}
+}
+
+// WithConfig 按配置设置服务器各项参数
+func WithConfig(cfg *zconfig.Config) Option {
+	return func(s *Server) {
+		if cfg.Name != "" {
+			s.Name = cfg.Name
+		}
+		if cfg.IPVersion != "" {
+			s.IPVersion = cfg.IPVersion
+		}
+		s.IP = cfg.Host
+		s.Port = cfg.Port
+		s.maxConn = cfg.MaxConn
+		if cfg.MaxMsgChanLen > 0 {
+			s.maxMsgChanLen = cfg.MaxMsgChanLen
+		}
+		s.workerPoolSize = cfg.WorkerPoolSize
+		s.maxWorkerTaskLen = cfg.MaxWorkerTaskLen
+		s.maxPacketSize = cfg.MaxPacketSize
+		s.readTimeout = cfg.ReadTimeout.Std()
+		s.writeTimeout = cfg.WriteTimeout.Std()
+		if t, err := ParseTransport(cfg.Transport); err == nil {
+			s.transport = t
+		} else {
+			fmt.Printf("[WARN]%v, using default transport\n", err)
+		}
+		if cfg.EventLoops > 0 {
+			s.eventLoops = cfg.EventLoops
+		}
+		s.reusePort = cfg.ReusePort
+		if cfg.Prefork {
+			s.master = &prefork.Master{Children: cfg.PreforkChildren}
+		}
+		if cfg.Codec != "" {
+			if c, err := zcodec.Get(cfg.Codec); err == nil {
+				s.codec = c
+			} else {
+				fmt.Printf("[WARN]%v, using default codec\n", err)
+			}
+		}
+		if cfg.GracefulRestart {
+			s.restarter = &graceful.Restarter{}
+		}
+		if n, err := ParseNetwork(cfg.Network); err == nil {
+			s.network = n
+		} else {
+			fmt.Printf("[WARN]%v, using tcp\n", err)
+		}
+		s.socketPath = cfg.SocketPath
+		if perm, err := cfg.SocketFileMode(); err == nil {
+			s.socketPerm = perm
+		} else {
+			fmt.Printf("[WARN]%v, keeping default socket permission\n", err)
+		}
+		if cfg.UDPSessionTimeout > 0 {
+			s.udpTimeout = cfg.UDPSessionTimeout.Std()
+		}
+		if cfg.TLS.Enabled() {
+			files := cfg.TLS
+			s.tlsFiles = &files
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package znet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+var (
+	ErrPacketTooLarge = errors.New("znet: packet too large")
+	ErrMalformedFrame = errors.New("znet: malformed frame")
+)
+
+// ProtocolErrorAction 收到超限或畸形帧时的处理方式
+type ProtocolErrorAction uint8
+
+const (
+	// ProtocolErrorClose 直接关闭连接
+	ProtocolErrorClose ProtocolErrorAction = iota
+	// ProtocolErrorReply 先同步回复 MsgIDError 错误帧再关闭连接
+	ProtocolErrorReply
+	// ProtocolErrorSkip 超限帧的数据被读出丢弃（不分配内存）后继续处理后续帧；
+	// 畸形帧无法重新定位帧边界，仍按 ProtocolErrorReply 处理
+	ProtocolErrorSkip
+)
+
+// protocolError 统计、记录并回调一次协议错误，返回实际应采取的处理方式：
+// 只有超限帧可以跳过，畸形帧的 Skip 按 Reply 处理
+func (s *Server) protocolError(conn ziface.IConnection, err error) ProtocolErrorAction {
+	tooLarge := errors.Is(err, ErrPacketTooLarge)
+	if tooLarge {
+		s.oversizeCount.Add(1)
+	} else {
+		s.malformedCount.Add(1)
+	}
+	fmt.Printf("[ERROR]Conn %d protocol error, err: %v\n", conn.GetConnID(), err)
+	if s.onProtocolError != nil {
+		s.onProtocolError(conn, err)
+	}
+	if s.protocolErrorAction == ProtocolErrorSkip && !tooLarge {
+		return ProtocolErrorReply
+	}
+	return s.protocolErrorAction
+}
+
+// protocolError 处理读循环中的协议错误，返回 true 表示可以继续读下一帧
+func (c *Connection) protocolError(err error, msg ziface.IMessage) bool {
+	switch c.server.protocolError(c, err) {
+	case ProtocolErrorSkip:
+		if _, derr := io.CopyN(io.Discard, c.conn, int64(msg.GetDataLen())); derr == nil {
+			_ = c.SendBuffered(MsgIDError, []byte(err.Error()))
+			return true
+		}
+	case ProtocolErrorReply:
+		_ = c.Send(MsgIDError, []byte(err.Error()))
+	}
+	return false
+}
+
+// ProtocolStats 返回协议错误计数
+func (s *Server) ProtocolStats() ziface.ProtocolStats {
+	return ziface.ProtocolStats{
+		Oversize:  s.oversizeCount.Load(),
+		Malformed: s.malformedCount.Load(),
+	}
+}
+
+func oversize(msg ziface.IMessage, limit uint32) error {
+	return fmt.Errorf("%w: msgID %d length %d exceeds %d", ErrPacketTooLarge, msg.GetMsgID(), msg.GetDataLen(), limit)
+}
+
+func malformed(err error) error {
+	return fmt.Errorf("%w: %v", ErrMalformedFrame, err)
+}
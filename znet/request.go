@@ -0,0 +1,44 @@
+package znet
+
+import (
+	"github.com/SparkleBo/zinx/zcodec"
+	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/zpool"
+)
+
+// Request 连接与消息的组合，作为处理函数的入参
+type Request struct {
+	conn ziface.IConnection
+	msg  ziface.IMessage
+	// codec 服务器配置的编解码器
+	codec zcodec.Codec
+	// pooled 消息数据来自缓冲池，处理函数返回后归还
+	pooled bool
+}
+
+func (r *Request) GetConnection() ziface.IConnection { return r.conn }
+func (r *Request) GetMsgID() uint32                  { return r.msg.GetMsgID() }
+func (r *Request) GetData() []byte                   { return r.msg.GetData() }
+func (r *Request) GetMessage() ziface.IMessage       { return r.msg }
+
+func (r *Request) Bind(v any) error { return r.codec.Decode(r.GetData(), v) }
+
+func (r *Request) Reply(msgID uint32, v any) error {
+	data, err := r.codec.Encode(v)
+	if err != nil {
+		return err
+	}
+	return r.conn.Send(msgID, data)
+}
+
+// release 归还池化的消息数据，之后 GetData 返回 nil
+func (r *Request) release() {
+	if !r.pooled {
+		return
+	}
+	r.pooled = false
+	zpool.Put(r.msg.GetData())
+	r.msg.SetData(nil)
+}
+
+var _ ziface.IRequest = (*Request)(nil)
@@ -1,79 +1,540 @@
 package znet
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/SparkleBo/zinx/internal/graceful"
+	"github.com/SparkleBo/zinx/internal/prefork"
+	"github.com/SparkleBo/zinx/internal/reuseport"
+	"github.com/SparkleBo/zinx/zcodec"
+	"github.com/SparkleBo/zinx/zconfig"
 	"github.com/SparkleBo/zinx/ziface"
 )
 
+var (
+	ErrServerNotRunning = errors.New("znet: server not running")
+	ErrRestartDisabled  = errors.New("znet: graceful restart not enabled")
+	ErrRestartPrefork   = errors.New("znet: graceful restart not supported in prefork mode")
+	ErrRestartUDP       = errors.New("znet: graceful restart not supported for udp")
+)
+
 type Server struct {
 	Name string
 	IPVersion string
 	IP string
 	Port int
+
+	// 消息路由与封包实现
+	msgHandler ziface.IMsgHandle
+	packet     ziface.IDataPack
+	// packPooled 封包器为 DataPack 时封包结果来自 zpool，发送完可归还
+	packPooled bool
+	// codec 消息数据的编解码器，供 Request.Bind/Reply 与 Typed 使用
+	codec zcodec.Codec
+	// network 监听的网络类型：NetworkTCP（按 IPVersion 区分 IP 族）、NetworkUnix 或 NetworkUDP
+	network    string
+	socketPath string
+	socketPerm os.FileMode
+	listeners  []net.Listener
+	// udp 数据报监听与按对端地址划分的伪会话，仅 NetworkUDP 下非 nil
+	udp        *udpListener
+	udpTimeout time.Duration
+	// reusePort > 0 时以 SO_REUSEPORT 创建多个监听；master 非 nil 表示启用 prefork
+	reusePort int
+	master    *prefork.Master
+	// restarter 非 nil 表示启用不停机重启：优先接管父进程传入的监听，收到 SIGUSR2 时拉起新进程
+	restarter *graceful.Restarter
+
+	// 连接 I/O 模型，poller 仅在 TransportEpoll 下非 nil
+	transport  Transport
+	eventLoops int
+	poller     poller
+
+	// TLS：tlsConfig 非 nil 时 accept 后先在独立 goroutine 中完成握手再建立连接；
+	// tlsFiles 为配置中的证书文件，Start 时加载到 tlsConfig
+	tlsConfig        *tls.Config
+	tlsFiles         *zconfig.TLSConfig
+	handshakeTimeout time.Duration
+
+	// 单条消息数据上限与读写超时
+	maxPacketSize uint32
+	readTimeout   time.Duration
+	writeTimeout  time.Duration
+
+	// 连接管理
+	connMgr       ziface.IConnManager
+	groupMgr      ziface.IGroupManager
+	connID        atomic.Uint64
+	maxConn       int
+	maxMsgChanLen int
+
+	// 发送队列反压：队列长度达到 sendHighWater 暂停读取，降到 sendLowWater 恢复
+	sendHighWater  int
+	sendLowWater   int
+	overflowPolicy OverflowPolicy
+
+	// 工作池
+	workerPoolSize   int
+	maxWorkerTaskLen int
+	overloadPolicy   OverloadPolicy
+
+	// 协议错误处理与计数
+	protocolErrorAction ProtocolErrorAction
+	onProtocolError     func(conn ziface.IConnection, err error)
+	oversizeCount       atomic.Uint64
+	malformedCount      atomic.Uint64
+
+	// 心跳检测，nil 表示未启用
+	heartbeatCfg *HeartbeatConfig
+	heartbeat    *heartbeatChecker
+
+	// 连接生命周期钩子
+	onAccept    func(conn net.Conn) error
+	onConnStart func(conn ziface.IConnection)
+	onConnStop  func(conn ziface.IConnection)
+
+	// 生命周期：draining 置位后读协程退出不再关闭连接，由 Stop 统一收尾；
+	// ctx 在 Stop 开始时取消，用于中断进行中的 TLS 握手
+	ctx        context.Context
+	cancel     context.CancelFunc
+	draining   atomic.Bool
+	readers    sync.WaitGroup
+	acceptors  sync.WaitGroup
+	running    atomic.Bool
+	stopOnce   sync.Once
+	exit       chan struct{}
 }
 
 func (s *Server) Start() {
 	fmt.Printf("[START]Server Name: %s, IPVersion: %s, IP: %s, Port: %d\n", s.Name, s.IPVersion, s.IP, s.Port)
-	go func() {
-		addr, err := net.ResolveTCPAddr(s.IPVersion, fmt.Sprintf("%s:%d", s.IP, s.Port))
+	// SO_REUSEPORT 与 prefork 依赖多个监听共享 TCP 端口，在派生子进程前检查
+	if s.network != NetworkTCP && (s.reusePort > 0 || s.master != nil) {
+		fmt.Printf("[ERROR]Network %s does not support reuseport or prefork\n", s.network)
+		return
+	}
+	// UDP 全部会话共用一个读循环，不能在其中同步执行处理函数
+	if s.network == NetworkUDP && s.workerPoolSize <= 0 {
+		fmt.Printf("[ERROR]Network udp requires a worker pool\n")
+		return
+	}
+	// prefork 父进程只负责派生与监管子进程，不监听端口
+	if s.master != nil && !prefork.IsChild() {
+		if err := s.master.Start(); err != nil {
+			fmt.Printf("[ERROR]Prefork start failed, err: %v\n", err)
+			return
+		}
+		s.running.Store(true)
+		go s.watchMaster()
+		println("Server Start")
+		return
+	}
+	if s.tlsConfig == nil && s.tlsFiles != nil {
+		cfg, err := s.tlsFiles.Load()
 		if err != nil {
-			fmt.Printf("[ERROR]ResolveTCPAddr failed, err: %v\n", err)
+			fmt.Printf("[ERROR]Load TLS config failed, err: %v\n", err)
 			return
 		}
-		// 监听 TCP 地址
+		s.tlsConfig = cfg
+	}
+	// 监听地址（同步完成，Start 返回后即可接受连接）
+	var err error
+	if s.network == NetworkUDP {
+		var pc *net.UDPConn
+		if pc, err = s.listenUDP(); err == nil {
+			s.udp = newUDPListener(s, pc)
+		}
+	} else {
+		s.listeners, err = s.listen()
+	}
+	if err != nil {
+		fmt.Printf("[ERROR]Listen failed, err: %v\n", err)
+		return
+	}
+	if s.transport == TransportEpoll && s.tlsConfig != nil {
+		// 事件循环直接读写 fd，无法叠加 TLS 记录层
+		fmt.Printf("[WARN]Epoll transport does not support TLS, fallback to goroutine\n")
+	} else if s.transport == TransportEpoll && s.udp != nil {
+		fmt.Printf("[WARN]Epoll transport does not support udp, fallback to goroutine\n")
+	} else if s.transport == TransportEpoll {
+		if s.poller, err = newPoller(s, s.eventLoops); err != nil {
+			fmt.Printf("[WARN]Epoll transport unavailable, fallback to goroutine, err: %v\n", err)
+		}
+	}
+	s.msgHandler.StartWorkerPool()
+	if s.heartbeat != nil {
+		s.heartbeat.start()
+	}
+	// 每个监听一个 accept 协程，UDP 由单个协程读取全部数据报
+	for _, l := range s.listeners {
+		s.acceptors.Add(1)
+		go s.acceptLoop(l)
+	}
+	if s.udp != nil {
+		s.acceptors.Add(1)
+		go s.udp.serve()
+	}
+	s.running.Store(true)
+	// 由不停机重启拉起时通知旧进程可以退出
+	if s.restarter != nil {
+		graceful.Ready()
+	}
+	println("Server Start")
+}
+
+// listen 启用不停机重启且父进程传入了同一地址的监听时直接接管；unix socket 创建单个监听；
+// 未启用 SO_REUSEPORT 时创建单个普通监听；启用时创建 reusePort 个共享同一端口的监听，
+// prefork 子进程至少创建一个，以便与兄弟进程共享端口
+func (s *Server) listen() ([]net.Listener, error) {
+	network, addr := s.IPVersion, fmt.Sprintf("%s:%d", s.IP, s.Port)
+	if s.network == NetworkUnix {
+		network, addr = NetworkUnix, s.socketPath
+	}
+	if s.restarter != nil {
+		inherited, err := graceful.Take(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if len(inherited) > 0 {
+			return inherited, nil
+		}
+	}
+	if s.network == NetworkUnix {
+		l, err := listenUnix(s.socketPath, s.socketPerm)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{l}, nil
+	}
+	n := s.reusePort
+	if n <= 0 && s.master != nil {
+		n = 1
+	}
+	if n <= 0 {
+		addr, err := net.ResolveTCPAddr(s.IPVersion, addr)
+		if err != nil {
+			return nil, err
+		}
 		l, err := net.ListenTCP(s.IPVersion, addr)
 		if err != nil {
-			fmt.Printf("[ERROR]ListenTCP failed, err: %v\n", err)
-			return
+			return nil, err
 		}
-		defer l.Close()
-		// 启动 server 网络连接业务
-		for {
-			conn, err := l.AcceptTCP()
-			if err != nil {
-				fmt.Printf("[ERROR]AcceptTCP failed, err: %v\n", err)
+		return []net.Listener{l}, nil
+	}
+	listeners := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		l, err := reuseport.Listen(s.IPVersion, addr)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+		// 端口为 0 时后续监听绑定到第一个监听分配到的端口
+		addr = l.Addr().String()
+	}
+	return listeners, nil
+}
+
+// acceptLoop 启动 server 网络连接业务，监听关闭后退出
+func (s *Server) acceptLoop(l net.Listener) {
+	defer s.acceptors.Done()
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			fmt.Printf("[ERROR]Accept failed, err: %v\n", err)
+			continue
+		}
+		// 超过最大连接数直接拒绝
+		if s.maxConn > 0 && s.connMgr.Len() >= s.maxConn {
+			fmt.Printf("[WARN]Too many connections, max: %d\n", s.maxConn)
+			_ = conn.Close()
+			continue
+		}
+		if s.onAccept != nil {
+			if err := s.onAccept(conn); err != nil {
+				fmt.Printf("[WARN]Conn from %s rejected, err: %v\n", conn.RemoteAddr(), err)
+				_ = conn.Close()
 				continue
 			}
-			// 针对每个 connection 都启动一个 goroutine
-			go func() {
-				for {
-					buf := make([]byte, 512)
-					n, err := conn.Read(buf)
-					if err != nil {
-						fmt.Printf("[ERROR]Read failed, err: %v\n", err)
-						continue
-					}
-					fmt.Printf("[RECV]%s\n", buf[:n])
-					// 回显
-					if _, err := conn.Write(buf[:n]); err != nil {
-						fmt.Printf("[ERROR]Write failed, err: %v\n", err)
-						continue
-					}
+		}
+		if s.tlsConfig != nil {
+			s.acceptors.Add(1)
+			go s.handshake(conn)
+			continue
+		}
+		s.startConn(conn)
+	}
+}
+
+// handshake 在握手超时或 Stop 前完成 TLS 握手，成功后建立连接；计入 acceptors，Stop 会等待其结束
+func (s *Server) handshake(conn net.Conn) {
+	defer s.acceptors.Done()
+	ctx, cancel := context.WithTimeout(s.ctx, s.handshakeTimeout)
+	defer cancel()
+	tc := tls.Server(conn, s.tlsConfig)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		fmt.Printf("[WARN]TLS handshake with %s failed, err: %v\n", conn.RemoteAddr(), err)
+		_ = conn.Close()
+		return
+	}
+	s.serveConn(tc)
+}
+
+// Addr 返回实际监听地址（端口为 0 时可据此获得分配的端口），未监听时返回 nil
+func (s *Server) Addr() net.Addr {
+	if s.udp != nil {
+		return s.udp.pc.LocalAddr()
+	}
+	if len(s.listeners) == 0 {
+		return nil
+	}
+	return s.listeners[0].Addr()
+}
+
+// startConn 事件循环模式下交给 poller，否则为连接启动独立的读写 goroutine
+func (s *Server) startConn(conn net.Conn) {
+	if s.poller != nil {
+		if _, err := s.poller.add(conn, s.connID.Add(1)); err != nil {
+			fmt.Printf("[ERROR]Conn from %s register to event loop failed, err: %v\n", conn.RemoteAddr(), err)
+			_ = conn.Close()
+		}
+		return
+	}
+	s.serveConn(conn)
+}
+
+// serveConn 为连接启动独立的读写 goroutine
+func (s *Server) serveConn(conn net.Conn) {
+	c := NewConnection(s, conn, s.connID.Add(1))
+	s.connMgr.Add(c)
+	c.Start()
+}
+
+// Stop 关闭监听、停止读取新消息，等待读协程与工作池处理完在途消息、
+// 发送队列写空后关闭连接；ctx 到期则强制关闭剩余连接
+func (s *Server) Stop(ctx context.Context) error {
+	var err error
+	s.stopOnce.Do(func() {
+		fmt.Printf("[STOP]Server Name: %s, IPVersion: %s, IP: %s, Port: %d\n", s.Name, s.IPVersion, s.IP, s.Port)
+		s.draining.Store(true)
+		s.cancel()
+		if s.heartbeat != nil {
+			s.heartbeat.stop()
+		}
+		if s.master != nil && !prefork.IsChild() {
+			err = s.master.Stop(ctx)
+			close(s.exit)
+			return
+		}
+		for _, l := range s.listeners {
+			_ = l.Close()
+		}
+		if s.udp != nil {
+			s.udp.stopReading()
+		}
+		s.acceptors.Wait()
+		// 停止读取新消息，正在处理的消息继续执行
+		s.connMgr.Range(func(conn ziface.IConnection) bool {
+			if d, ok := conn.(drainer); ok {
+				d.stopReading()
+			}
+			return true
+		})
+
+		drained := make(chan struct{})
+		go func() {
+			s.readers.Wait()
+			// 事件循环模式的连接不计入 readers，须等各循环分发完在途的读事件再停止工作池
+			if s.poller != nil {
+				s.poller.quiesce()
+			}
+			s.msgHandler.StopWorkerPool()
+			s.connMgr.Range(func(conn ziface.IConnection) bool {
+				if d, ok := conn.(drainer); ok {
+					d.flush()
 				}
-			}()
+				return true
+			})
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			err = ctx.Err()
 		}
-	}()
-	println("Server Start")
+		s.connMgr.ClearConn()
+		if s.poller != nil {
+			s.poller.stop()
+		}
+		if s.udp != nil {
+			_ = s.udp.pc.Close()
+		}
+		close(s.exit)
+	})
+	return err
+}
+
+// watchMaster prefork 父进程在子进程反复崩溃、放弃监管后自行停止，Serve 随之返回，原因见 Err
+func (s *Server) watchMaster() {
+	select {
+	case <-s.master.Failed():
+	case <-s.exit:
+		return
+	}
+	fmt.Printf("[ERROR]Prefork children keep crashing, err: %v\n", s.master.Err())
+	ctx, cancel := context.WithTimeout(context.Background(), defaultStopTimeout)
+	defer cancel()
+	_ = s.Stop(ctx)
 }
 
-func (s *Server) Stop() {
-	fmt.Printf("[STOP]Server Name: %s, IPVersion: %s, IP: %s, Port: %d\n", s.Name, s.IPVersion, s.IP, s.Port)
+// Err 返回服务器自行停止的原因，目前仅有 prefork 子进程反复崩溃一种情况
+func (s *Server) Err() error {
+	if s.master == nil {
+		return nil
+	}
+	return s.master.Err()
 }
 
+// Serve 启动并阻塞，直到 Stop 完成
 func (s *Server) Serve() {
 	s.Start()
+	if !s.running.Load() {
+		return
+	}
+	// prefork 子进程随父进程退出或收到终止信号时优雅停止
+	if s.master != nil && prefork.IsChild() {
+		prefork.WatchParent(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), defaultStopTimeout)
+			defer cancel()
+			_ = s.Stop(ctx)
+		})
+	}
+	if s.restarter != nil {
+		stop := graceful.NotifyRestart(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), defaultStopTimeout)
+			defer cancel()
+			if err := s.Restart(ctx); err != nil {
+				fmt.Printf("[ERROR]Graceful restart failed, err: %v\n", err)
+			}
+		})
+		defer stop()
+	}
+	<-s.exit
+}
+
+// Restart 不停机重启：拉起新进程并把监听交给它，新进程就绪后优雅停止当前服务器；
+// 新进程启动失败时当前服务器继续运行。需启用 WithGracefulRestart，prefork 模式下不支持
+func (s *Server) Restart(ctx context.Context) error {
+	if s.restarter == nil {
+		return ErrRestartDisabled
+	}
+	if s.master != nil {
+		return ErrRestartPrefork
+	}
+	if s.network == NetworkUDP {
+		return ErrRestartUDP
+	}
+	if !s.running.Load() || s.draining.Load() {
+		return ErrServerNotRunning
+	}
+	proc, err := s.restarter.Restart(s.listeners)
+	if err != nil {
+		return err
+	}
+	// socket 文件已由新进程接管，关闭监听时不能删除
+	for _, l := range s.listeners {
+		if ul, ok := l.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	fmt.Printf("[RESTART]Server Name: %s, new process %d ready, draining\n", s.Name, proc.Pid)
+	return s.Stop(ctx)
+}
+
+// Codec 返回服务器使用的编解码器
+func (s *Server) Codec() zcodec.Codec { return s.codec }
+
+// GetConnMgr 返回连接管理器，可据此按 ID 查找连接并跨 goroutine 发送
+func (s *Server) GetConnMgr() ziface.IConnManager { return s.connMgr }
+
+// GetGroupMgr 返回连接分组管理器
+func (s *Server) GetGroupMgr() ziface.IGroupManager { return s.groupMgr }
+
+// Broadcast 向全部连接推送消息，不阻塞调用方，返回成功入队的连接数
+func (s *Server) Broadcast(msgID uint32, data []byte) int {
+	var conns []ziface.IConnection
+	s.connMgr.Range(func(conn ziface.IConnection) bool {
+		conns = append(conns, conn)
+		return true
+	})
+	return broadcast(s.packet, conns, msgID, data)
+}
 
-	select{}
+func (s *Server) SetOnAccept(fn func(conn net.Conn) error)        { s.onAccept = fn }
+func (s *Server) SetOnConnStart(fn func(conn ziface.IConnection)) { s.onConnStart = fn }
+func (s *Server) SetOnConnStop(fn func(conn ziface.IConnection))  { s.onConnStop = fn }
+
+func (s *Server) SetOnProtocolError(fn func(conn ziface.IConnection, err error)) {
+	s.onProtocolError = fn
 }
 
+// AddRouter 为指定 MsgID 注册处理函数
+func (s *Server) AddRouter(msgID uint32, h ziface.MsgHandler) {
+	s.msgHandler.AddRouter(msgID, h)
+}
 
-func NewServer(name string) ziface.IServer {
-	return &Server{
+func NewServer(name string, opts ...Option) ziface.IServer {
+	s := &Server{
 		Name: name,
 		IPVersion: "tcp4",
+		network: NetworkTCP,
 		IP: "127.0.0.1",
 		Port: 8888,
+		packet: NewDataPack(),
+		codec: zcodec.JSON{},
+		connMgr: NewConnManager(),
+		maxConn: defaultMaxConn,
+		maxMsgChanLen: defaultMaxMsgChanLen,
+		workerPoolSize: defaultWorkerPoolSize,
+		maxWorkerTaskLen: defaultMaxWorkerTaskLen,
+		maxPacketSize: defaultMaxPacketSize,
+		eventLoops: runtime.GOMAXPROCS(0),
+		handshakeTimeout: defaultHandshakeTimeout,
+		udpTimeout: defaultUDPSessionTimeout,
+		exit: make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.sendHighWater <= 0 || s.sendHighWater > s.maxMsgChanLen {
+		s.sendHighWater = s.maxMsgChanLen
 	}
+	s.sendLowWater = s.sendHighWater / 2
+	_, s.packPooled = s.packet.(*DataPack)
+	s.groupMgr = NewGroupManager(s.packet)
+	s.msgHandler = NewMsgHandle(s.workerPoolSize, s.maxWorkerTaskLen, s.overloadPolicy)
+	if s.heartbeatCfg != nil {
+		s.heartbeat = newHeartbeatChecker(*s.heartbeatCfg, s.packet)
+	}
+	return s
+}
+
+// NewServerWithConfig 按配置构造服务器，opts 在配置之后应用，可覆盖配置项
+func NewServerWithConfig(cfg *zconfig.Config, opts ...Option) ziface.IServer {
+	return NewServer(cfg.Name, append([]Option{WithConfig(cfg)}, opts...)...)
 }
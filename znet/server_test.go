@@ -1,43 +1,1420 @@
 package znet
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"math/big"
 	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/SparkleBo/zinx/internal/graceful"
+	"github.com/SparkleBo/zinx/internal/prefork"
+	"github.com/SparkleBo/zinx/zcodec"
+	"github.com/SparkleBo/zinx/zconfig"
+	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/zpool"
 )
 
-func ClientTest() {
-	fmt.Println("Client Test")
-	time.Sleep(3 * time.Second)
-	 conn, err := net.DialTCP("tcp4", nil, &net.TCPAddr{
-		IP:   net.ParseIP("127.0.0.1"),
-		Port: 8888,
-	})
+// newTestServer 在随机端口上启动服务器
+func newTestServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	s := NewServer("test", opts...).(*Server)
+	s.Port = 0
+	return s
+}
+
+// readMsg 从连接中按默认 TLV 格式读出一条消息
+func readMsg(t *testing.T, conn net.Conn) ziface.IMessage {
+	t.Helper()
+	dp := NewDataPack()
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	head := make([]byte, dp.GetHeadLen())
+	if _, err := io.ReadFull(conn, head); err != nil {
+		t.Fatalf("read head: %v", err)
+	}
+	msg, err := dp.Unpack(head)
+	if err != nil {
+		t.Fatalf("unpack: %v", err)
+	}
+	data := make([]byte, msg.GetDataLen())
+	if _, err := io.ReadFull(conn, data); err != nil {
+		t.Fatalf("read data: %v", err)
+	}
+	msg.SetData(data)
+	return msg
+}
+
+func writeMsg(t *testing.T, conn net.Conn, id uint32, data []byte) {
+	t.Helper()
+	buf, err := NewDataPack().Pack(NewMessage(id, data))
 	if err != nil {
-		fmt.Printf("[ERROR]DialTCP failed, err: %v\n", err)
+		t.Fatalf("pack: %v", err)
+	}
+	if _, err := conn.Write(buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestDataPack_RoundTrip(t *testing.T) {
+	dp := NewDataPack()
+	buf, err := dp.Pack(NewMessage(7, []byte("hello")))
+	if err != nil { t.Fatal(err) }
+	if uint32(len(buf)) != dp.GetHeadLen()+5 { t.Fatalf("unexpected frame length %d", len(buf)) }
+	msg, err := dp.Unpack(buf[:dp.GetHeadLen()])
+	if err != nil { t.Fatal(err) }
+	if msg.GetMsgID() != 7 || msg.GetDataLen() != 5 {
+		t.Fatalf("unexpected head: id=%d len=%d", msg.GetMsgID(), msg.GetDataLen())
+	}
+	if _, err := dp.Unpack(buf[:3]); err == nil { t.Fatalf("short head should fail") }
+}
+
+func TestServer_Dispatch(t *testing.T) {
+	s := newTestServer(t)
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		return req.GetConnection().Send(2, req.GetData())
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+
+	// 两帧连续写入，验证拆包边界
+	writeMsg(t, conn, 1, []byte("hello"))
+	writeMsg(t, conn, 1, []byte("world"))
+	for _, want := range []string{"hello", "world"} {
+		msg := readMsg(t, conn)
+		if msg.GetMsgID() != 2 || string(msg.GetData()) != want {
+			t.Fatalf("unexpected reply: id=%d data=%q", msg.GetMsgID(), msg.GetData())
+		}
+	}
+}
+
+// waitFor 轮询等待条件成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met before deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServer_ConnManager(t *testing.T) {
+	s := newTestServer(t, WithMaxConn(1))
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	waitFor(t, func() bool { return s.GetConnMgr().Len() == 1 })
+
+	// 按 ID 查找连接并从其它 goroutine 推送
+	c, err := s.GetConnMgr().Get(1)
+	if err != nil { t.Fatal(err) }
+	c.SetProperty("uid", 42)
+	if v, ok := c.GetProperty("uid"); !ok || v.(int) != 42 { t.Fatalf("property lost: %v", v) }
+	go func() { _ = c.SendBuffered(3, []byte("push")) }()
+	if msg := readMsg(t, conn); msg.GetMsgID() != 3 || string(msg.GetData()) != "push" {
+		t.Fatalf("unexpected push: id=%d data=%q", msg.GetMsgID(), msg.GetData())
+	}
+	if _, err := s.GetConnMgr().Get(99); err != ErrConnNotFound { t.Fatalf("expected ErrConnNotFound, got %v", err) }
+
+	// 超过最大连接数的连接会被直接关闭
+	extra, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer extra.Close()
+	_ = extra.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := extra.Read(make([]byte, 1)); err != io.EOF { t.Fatalf("expected EOF on rejected conn, got %v", err) }
+
+	// Stop 关闭全部连接
+	if err := s.Stop(context.Background()); err != nil { t.Fatal(err) }
+	if s.GetConnMgr().Len() != 0 { t.Fatalf("connections not cleared") }
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF { t.Fatalf("expected EOF after Stop, got %v", err) }
+	if err := c.Send(3, nil); err != ErrConnClosed { t.Fatalf("expected ErrConnClosed, got %v", err) }
+}
+
+func TestServer_WorkerPoolOrdering(t *testing.T) {
+	s := newTestServer(t, WithWorkerPool(4, 8))
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		return req.GetConnection().SendBuffered(2, req.GetData())
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	for i := 0; i < 100; i++ {
+		writeMsg(t, conn, 1, []byte{byte(i)})
+	}
+	for i := 0; i < 100; i++ {
+		if msg := readMsg(t, conn); msg.GetData()[0] != byte(i) {
+			t.Fatalf("out of order: want %d, got %d", i, msg.GetData()[0])
+		}
+	}
+}
+
+func TestServer_WorkerPoolReject(t *testing.T) {
+	s := newTestServer(t, WithWorkerPool(1, 1), WithOverloadPolicy(PolicyReject))
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return req.GetConnection().SendBuffered(2, req.GetData())
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+
+	// 第一条占住 worker，第二条进入队列，第三条被拒绝
+	writeMsg(t, conn, 1, []byte("a"))
+	<-started
+	writeMsg(t, conn, 1, []byte("b"))
+	writeMsg(t, conn, 1, []byte("c"))
+	if msg := readMsg(t, conn); msg.GetMsgID() != MsgIDError {
+		t.Fatalf("expected error frame, got msgID %d", msg.GetMsgID())
+	}
+	close(release)
+	for _, want := range []string{"a", "b"} {
+		if msg := readMsg(t, conn); string(msg.GetData()) != want {
+			t.Fatalf("want %q, got %q", want, msg.GetData())
+		}
+	}
+}
+
+func TestServer_GracefulStop(t *testing.T) {
+	s := newTestServer(t)
+	started := make(chan struct{})
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		return req.GetConnection().SendBuffered(2, req.GetData())
+	})
+	s.Start()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	writeMsg(t, conn, 1, []byte("inflight"))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil { t.Fatalf("stop: %v", err) }
+
+	// 在途消息的回复先送达，随后连接关闭
+	if msg := readMsg(t, conn); string(msg.GetData()) != "inflight" {
+		t.Fatalf("unexpected reply %q", msg.GetData())
+	}
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF { t.Fatalf("expected EOF, got %v", err) }
+	if _, err := net.Dial("tcp", s.Addr().String()); err == nil { t.Fatalf("listener still accepting") }
+	// Serve 阻塞在 exit 上，Stop 完成后应已关闭
+	select {
+	case <-s.exit:
+	default:
+		t.Fatalf("exit not closed after Stop")
+	}
+}
+
+func TestServer_StopDeadline(t *testing.T) {
+	s := newTestServer(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		close(started)
+		<-release
+		return nil
+	})
+	s.Start()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	writeMsg(t, conn, 1, nil)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); err != context.DeadlineExceeded { t.Fatalf("expected deadline exceeded, got %v", err) }
+	if s.GetConnMgr().Len() != 0 { t.Fatalf("connections not force closed") }
+}
+
+func TestServer_Hooks(t *testing.T) {
+	s := newTestServer(t)
+	s.SetOnAccept(func(conn net.Conn) error {
+		if s.GetConnMgr().Len() > 0 {
+			return errors.New("only one device")
+		}
+		return nil
+	})
+	s.SetOnConnStart(func(conn ziface.IConnection) { conn.SetProperty("session", "dev-1") })
+	stopped := make(chan any, 1)
+	s.SetOnConnStop(func(conn ziface.IConnection) {
+		v, _ := conn.GetProperty("session")
+		stopped <- v
+	})
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		v, _ := req.GetConnection().GetProperty("session")
+		return req.GetConnection().Send(2, []byte(v.(string)))
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	writeMsg(t, conn, 1, nil)
+	if msg := readMsg(t, conn); string(msg.GetData()) != "dev-1" { t.Fatalf("session not set on start: %q", msg.GetData()) }
+
+	// OnAccept 拒绝的连接不会进入读循环
+	rejected, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer rejected.Close()
+	_ = rejected.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := rejected.Read(make([]byte, 1)); err != io.EOF { t.Fatalf("expected EOF on rejected conn, got %v", err) }
+
+	conn.Close()
+	select {
+	case v := <-stopped:
+		if v != "dev-1" { t.Fatalf("OnConnStop saw property %v", v) }
+	case <-time.After(3 * time.Second):
+		t.Fatalf("OnConnStop not called")
+	}
+}
+
+func TestServer_HeartbeatIdle(t *testing.T) {
+	idle := make(chan uint64, 1)
+	s := newTestServer(t, WithHeartbeat(HeartbeatConfig{
+		Interval: 20 * time.Millisecond,
+		Timeout:  80 * time.Millisecond,
+		OnIdle:   func(conn ziface.IConnection) { idle <- conn.GetConnID() },
+	}))
+	s.Start()
+	defer s.Stop(context.Background())
+
+	alive, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer alive.Close()
+	dead, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer dead.Close()
+
+	// alive 持续发送 ping 并收到 pong，dead 保持沉默
+	for i := 0; i < 6; i++ {
+		writeMsg(t, alive, MsgIDHeartbeat, nil)
+		if msg := readMsg(t, alive); msg.GetMsgID() != MsgIDHeartbeat { t.Fatalf("expected pong, got %d", msg.GetMsgID()) }
+		time.Sleep(30 * time.Millisecond)
+	}
+	select {
+	case <-idle:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("OnIdle not called")
+	}
+	_ = dead.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := dead.Read(make([]byte, 1)); err != io.EOF { t.Fatalf("expected idle conn closed, got %v", err) }
+	if s.GetConnMgr().Len() != 1 { t.Fatalf("active conn should survive, have %d", s.GetConnMgr().Len()) }
+}
+
+func TestServer_HeartbeatSendPing(t *testing.T) {
+	s := newTestServer(t, WithHeartbeat(HeartbeatConfig{Interval: 20 * time.Millisecond, MsgID: 100, SendPing: true}))
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	if msg := readMsg(t, conn); msg.GetMsgID() != 100 { t.Fatalf("expected ping, got %d", msg.GetMsgID()) }
+}
+
+func TestServer_SendBackpressure(t *testing.T) {
+	s := newTestServer(t, WithWorkerPool(0, 0), WithMaxMsgChanLen(4), WithOverflowPolicy(OverflowDrop))
+	var handled atomic.Int32
+	payload := make([]byte, 64<<10)
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		handled.Add(1)
+		return req.GetConnection().SendBuffered(2, payload)
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	const total = 500
+	go func() {
+		buf, _ := NewDataPack().Pack(NewMessage(1, nil))
+		for i := 0; i < total; i++ {
+			if _, err := conn.Write(buf); err != nil { return }
+		}
+	}()
+
+	// 客户端不读，发送队列到达高水位后读协程暂停，处理数停止增长
+	time.Sleep(200 * time.Millisecond)
+	paused := handled.Load()
+	time.Sleep(100 * time.Millisecond)
+	if n := handled.Load(); n != paused || n >= total { t.Fatalf("reader not paused: %d -> %d", paused, n) }
+
+	// 开始读后全部消息被处理且没有回复被丢弃
+	for i := 0; i < total; i++ {
+		if msg := readMsg(t, conn); msg.GetDataLen() != uint32(len(payload)) { t.Fatalf("short reply %d", msg.GetDataLen()) }
+	}
+	if handled.Load() != total { t.Fatalf("expected %d handled, got %d", total, handled.Load()) }
+}
+
+func TestServer_SendOverflowClose(t *testing.T) {
+	s := newTestServer(t, WithMaxMsgChanLen(2), WithOverflowPolicy(OverflowClose))
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	waitFor(t, func() bool { return s.GetConnMgr().Len() == 1 })
+	c, _ := s.GetConnMgr().Get(1)
+
+	payload := make([]byte, 64<<10)
+	var err2 error
+	for i := 0; i < 10000 && err2 == nil; i++ {
+		err2 = c.SendBuffered(2, payload)
+	}
+	if err2 != ErrSendQueueFull { t.Fatalf("expected ErrSendQueueFull, got %v", err2) }
+	if c.Context().Err() == nil { t.Fatalf("slow consumer should be closed") }
+}
+
+func TestServer_Config(t *testing.T) {
+	cfg := zconfig.Default()
+	cfg.Port = 0
+	cfg.MaxPacketSize = 4
+	cfg.ReadTimeout = zconfig.Duration(100 * time.Millisecond)
+	s := NewServerWithConfig(cfg, WithWorkerPool(0, 0)).(*Server)
+	if s.maxPacketSize != 4 || s.workerPoolSize != 0 || s.readTimeout != 100*time.Millisecond {
+		t.Fatalf("config not applied: %+v", s)
+	}
+	s.AddRouter(1, func(req ziface.IRequest) error { return req.GetConnection().Send(1, req.GetData()) })
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	writeMsg(t, conn, 1, []byte("ok"))
+	readMsg(t, conn)
+	// 超过数据上限的帧导致连接关闭
+	writeMsg(t, conn, 1, []byte("too long"))
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	// 服务端关闭时缓冲区中仍有未读数据，客户端可能收到 RST 而非 EOF
+	if _, err := conn.Read(make([]byte, 1)); err == nil || os.IsTimeout(err) { t.Fatalf("expected closed conn, got %v", err) }
+
+	// 读超时内无数据的连接被关闭
+	idle, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer idle.Close()
+	_ = idle.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err != io.EOF { t.Fatalf("expected EOF on read timeout, got %v", err) }
+}
+
+// strictPack 拒绝 MsgID 为 0 的包头，用于模拟畸形帧
+type strictPack struct{ DataPack }
+
+func (p *strictPack) Unpack(head []byte) (ziface.IMessage, error) {
+	msg, err := p.DataPack.Unpack(head)
+	if err == nil && msg.GetMsgID() == 0 {
+		return nil, errors.New("msgID 0 is reserved")
+	}
+	return msg, err
+}
+
+func TestServer_ProtocolErrors(t *testing.T) {
+	hooked := make(chan error, 8)
+	s := newTestServer(t, WithMaxPacketSize(8), WithPacket(&strictPack{}), WithProtocolErrorAction(ProtocolErrorSkip))
+	s.SetOnProtocolError(func(conn ziface.IConnection, err error) { hooked <- err })
+	s.AddRouter(1, func(req ziface.IRequest) error { return req.GetConnection().Send(1, req.GetData()) })
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+
+	// 超限帧被跳过，回复错误帧后连接继续可用
+	writeMsg(t, conn, 1, make([]byte, 64))
+	writeMsg(t, conn, 1, []byte("ok"))
+	if msg := readMsg(t, conn); msg.GetMsgID() != MsgIDError { t.Fatalf("expected error frame, got %d", msg.GetMsgID()) }
+	if msg := readMsg(t, conn); string(msg.GetData()) != "ok" { t.Fatalf("conn unusable after skip: %q", msg.GetData()) }
+	if err := <-hooked; !errors.Is(err, ErrPacketTooLarge) { t.Fatalf("hook got %v", err) }
+
+	// 畸形包头无法跳过：回复错误帧后关闭
+	writeMsg(t, conn, 0, nil)
+	if msg := readMsg(t, conn); msg.GetMsgID() != MsgIDError { t.Fatalf("expected error frame, got %d", msg.GetMsgID()) }
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF { t.Fatalf("expected EOF, got %v", err) }
+	if err := <-hooked; !errors.Is(err, ErrMalformedFrame) { t.Fatalf("hook got %v", err) }
+
+	// 数据未写完即断开同样计为畸形帧
+	trunc, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	buf, _ := NewDataPack().Pack(NewMessage(1, []byte("12345678")))
+	_, _ = trunc.Write(buf[:len(buf)-3])
+	trunc.Close()
+	if err := <-hooked; !errors.Is(err, ErrMalformedFrame) { t.Fatalf("hook got %v", err) }
+
+	if st := s.ProtocolStats(); st.Oversize != 1 || st.Malformed != 2 { t.Fatalf("unexpected stats %+v", st) }
+}
+
+func TestServer_ProtocolErrorClose(t *testing.T) {
+	s := newTestServer(t, WithMaxPacketSize(8))
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	// 声明 2GB 的数据长度，服务端不应分配而是直接关闭
+	head := make([]byte, 8)
+	head[0], head[7] = 1, 0x80
+	_, _ = conn.Write(head)
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF { t.Fatalf("expected EOF, got %v", err) }
+	if st := s.ProtocolStats(); st.Oversize != 1 { t.Fatalf("unexpected stats %+v", st) }
+}
+
+func TestServer_GroupBroadcast(t *testing.T) {
+	s := newTestServer(t)
+	joined := make(chan ziface.IConnection, 4)
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		if err := s.GetGroupMgr().Join(string(req.GetData()), req.GetConnection()); err != nil { t.Errorf("join: %v", err) }
+		joined <- req.GetConnection()
+		return nil
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	dial := func(room string) (net.Conn, ziface.IConnection) {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil { t.Fatal(err) }
+		writeMsg(t, conn, 1, []byte(room))
+		return conn, <-joined
+	}
+	a, _ := dial("lobby")
+	b, serverB := dial("lobby")
+	c, _ := dial("game")
+	defer a.Close()
+	defer c.Close()
+
+	if n := s.GetGroupMgr().Broadcast("lobby", 5, []byte("hi")); n != 2 { t.Fatalf("expected 2 receivers, got %d", n) }
+	for _, conn := range []net.Conn{a, b} {
+		if msg := readMsg(t, conn); msg.GetMsgID() != 5 || string(msg.GetData()) != "hi" { t.Fatalf("unexpected %d %q", msg.GetMsgID(), msg.GetData()) }
+	}
+	if n := s.Broadcast(6, []byte("all")); n != 3 { t.Fatalf("expected 3 receivers, got %d", n) }
+	for _, conn := range []net.Conn{a, b, c} {
+		if msg := readMsg(t, conn); msg.GetMsgID() != 6 { t.Fatalf("unexpected msgID %d", msg.GetMsgID()) }
+	}
+
+	// 连接关闭后自动离开所有组
+	b.Close()
+	waitFor(t, func() bool { return len(s.GetGroupMgr().Members("lobby")) == 1 })
+	// 关闭后才处理到的 Join 不会把连接重新加入
+	if err := s.GetGroupMgr().Join("lobby", serverB); err != ErrConnClosed { t.Fatalf("expected ErrConnClosed, got %v", err) }
+	if n := len(s.GetGroupMgr().Members("lobby")); n != 1 { t.Fatalf("closed conn rejoined, members %d", n) }
+	s.GetGroupMgr().Leave("game", s.GetGroupMgr().Members("game")[0])
+	if groups := s.GetGroupMgr().Groups(); len(groups) != 1 || groups[0] != "lobby" { t.Fatalf("unexpected groups %v", groups) }
+}
+
+func TestServer_EpollTransport(t *testing.T) {
+	s := newTestServer(t, WithTransport(TransportEpoll, 2), WithMaxPacketSize(1<<20), WithWorkerPool(2, 64))
+	var stopped atomic.Int32
+	s.SetOnConnStart(func(conn ziface.IConnection) { conn.SetProperty("ok", true) })
+	s.SetOnConnStop(func(conn ziface.IConnection) { stopped.Add(1) })
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		if _, ok := req.GetConnection().GetProperty("ok"); !ok { return errors.New("OnConnStart not run") }
+		return req.GetConnection().SendBuffered(2, req.GetData())
+	})
+	s.Start()
+
+	conns := make([]net.Conn, 4)
+	for i := range conns {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil { t.Fatal(err) }
+		defer conn.Close()
+		conns[i] = conn
+	}
+	// 多帧合并写入与超过单次读缓冲区的大帧都能正确拆包
+	big := make([]byte, 300<<10)
+	for i := range big { big[i] = byte(i) }
+	for i, conn := range conns {
+		buf, _ := NewDataPack().Pack(NewMessage(1, []byte{byte(i)}))
+		buf2, _ := NewDataPack().Pack(NewMessage(1, big))
+		if _, err := conn.Write(append(append(buf, buf...), buf2...)); err != nil { t.Fatal(err) }
+	}
+	for i, conn := range conns {
+		for j := 0; j < 2; j++ {
+			if msg := readMsg(t, conn); msg.GetMsgID() != 2 || msg.GetData()[0] != byte(i) { t.Fatalf("conn %d unexpected reply %v", i, msg.GetData()) }
+		}
+		if msg := readMsg(t, conn); !bytes.Equal(msg.GetData(), big) { t.Fatalf("conn %d big frame corrupted", i) }
+	}
+	if n := s.Broadcast(3, []byte("all")); n != len(conns) { t.Fatalf("broadcast reached %d", n) }
+	for _, conn := range conns {
+		if msg := readMsg(t, conn); msg.GetMsgID() != 3 { t.Fatalf("unexpected msgID %d", msg.GetMsgID()) }
+	}
+
+	conns[0].Close()
+	waitFor(t, func() bool { return s.GetConnMgr().Len() == len(conns)-1 })
+	if err := s.Stop(context.Background()); err != nil { t.Fatal(err) }
+	for _, conn := range conns[1:] {
+		_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+		if _, err := conn.Read(make([]byte, 1)); err != io.EOF { t.Fatalf("expected EOF after Stop, got %v", err) }
+	}
+	if stopped.Load() != int32(len(conns)) { t.Fatalf("OnConnStop called %d times", stopped.Load()) }
+}
+
+func TestServer_EpollSendWaitsForWrite(t *testing.T) {
+	s := newTestServer(t, WithTransport(TransportEpoll, 1), WithWorkerPool(1, 16))
+	var sent atomic.Bool
+	big := make([]byte, 16<<20)
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		err := req.GetConnection().Send(2, big)
+		sent.Store(true)
+		return err
+	})
+	// 由其它 goroutine 关闭连接后立即接入的新连接可能复用同一 fd 号，数据不能串到旧连接上
+	s.AddRouter(3, func(req ziface.IRequest) error {
+		if err := req.GetConnection().Send(3, req.GetData()); err != nil { return err }
+		go req.GetConnection().Close()
+		return nil
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	writeMsg(t, conn, 1, nil)
+	// 对端不读时 16MB 无法一次写进内核缓冲区，Send 须阻塞到数据写出
+	time.Sleep(200 * time.Millisecond)
+	if sent.Load() { t.Fatalf("Send returned before data was written") }
+	if msg := readMsg(t, conn); len(msg.GetData()) != len(big) { t.Fatalf("short reply %d", len(msg.GetData())) }
+	waitFor(t, sent.Load)
+
+	for i := 0; i < 50; i++ {
+		c, err := net.Dial("tcp", s.Addr().String())
+		if err != nil { t.Fatal(err) }
+		payload := []byte(fmt.Sprint(i))
+		writeMsg(t, c, 3, payload)
+		if msg := readMsg(t, c); msg.GetMsgID() != 3 || !bytes.Equal(msg.GetData(), payload) { t.Fatalf("round %d unexpected reply %q", i, msg.GetData()) }
+		c.Close()
+	}
+}
+
+func TestServer_EpollWorkerBusy(t *testing.T) {
+	s := newTestServer(t, WithTransport(TransportEpoll, 1), WithWorkerPool(1, 1), WithOverloadPolicy(PolicyBlock))
+	release := make(chan struct{})
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		<-release
+		return nil
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+	defer close(release)
+
+	a, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer a.Close()
+	// 占住唯一的 worker 并填满队列后继续发送，事件循环不能被 PolicyBlock 阻塞
+	for i := 0; i < 4; i++ { writeMsg(t, a, 1, []byte("x")) }
+	time.Sleep(50 * time.Millisecond)
+
+	// 同一循环上的另一条连接仍能得到处理：畸形帧在事件循环中直接关闭连接
+	b, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer b.Close()
+	if _, err := b.Write(make([]byte, 3)); err != nil { t.Fatal(err) }
+	if cw, ok := b.(*net.TCPConn); ok { _ = cw.CloseWrite() }
+	_ = b.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := b.Read(make([]byte, 64)); err != io.EOF { t.Fatalf("event loop blocked by a full worker queue: %v", err) }
+}
+
+func TestServer_ReusePort(t *testing.T) {
+	s := newTestServer(t, WithReusePort(3))
+	s.AddRouter(1, func(req ziface.IRequest) error { return req.GetConnection().Send(1, req.GetData()) })
+	s.Start()
+	defer s.Stop(context.Background())
+	if len(s.listeners) != 3 { t.Fatalf("expected 3 listeners, got %d", len(s.listeners)) }
+	for _, l := range s.listeners {
+		if l.Addr().String() != s.Addr().String() { t.Fatalf("listeners bound to different addrs: %s %s", l.Addr(), s.Addr()) }
+	}
+	for i := 0; i < 10; i++ {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil { t.Fatal(err) }
+		writeMsg(t, conn, 1, []byte("x"))
+		readMsg(t, conn)
+		conn.Close()
+	}
+}
+
+// TestServer_Prefork 父进程派生的子进程会重新执行本测试，在子进程中直接 Serve
+func TestServer_Prefork(t *testing.T) {
+	if !prefork.IsChild() {
+		l, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil { t.Fatal(err) }
+		t.Setenv("ZNET_PREFORK_TEST_PORT", strconv.Itoa(l.Addr().(*net.TCPAddr).Port))
+		l.Close()
+	}
+	port, _ := strconv.Atoi(os.Getenv("ZNET_PREFORK_TEST_PORT"))
+	s := NewServer("prefork", WithPrefork(2)).(*Server)
+	s.Port = port
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		return req.GetConnection().Send(1, []byte(strconv.Itoa(os.Getpid())))
+	})
+	if prefork.IsChild() {
+		s.Serve()
+		return
+	}
+	s.master.Args = []string{"-test.run=^TestServer_Prefork$"}
+	s.Start()
+	defer s.Stop(context.Background())
+	if s.Addr() != nil { t.Fatalf("prefork master should not listen") }
+
+	askPid := func() string {
+		var conn net.Conn
+		waitFor(t, func() bool {
+			var err error
+			conn, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+			return err == nil
+		})
+		defer conn.Close()
+		writeMsg(t, conn, 1, nil)
+		return string(readMsg(t, conn).GetData())
+	}
+	if pid := askPid(); pid == strconv.Itoa(os.Getpid()) { t.Fatalf("request served by master") }
+
+	// 杀掉一个子进程后应被重新拉起
+	waitFor(t, func() bool { return len(s.master.Pids()) == 2 })
+	victim := s.master.Pids()[0]
+	if p, err := os.FindProcess(victim); err == nil { _ = p.Kill() }
+	waitFor(t, func() bool {
+		pids := s.master.Pids()
+		return len(pids) == 2 && pids[0] != victim && pids[1] != victim
+	})
+	askPid()
+}
+
+// TestServer_PreforkCrashLoop 子进程启动即退出时父进程按退避重启，达到上限后放弃并停止
+func TestServer_PreforkCrashLoop(t *testing.T) {
+	if prefork.IsChild() { os.Exit(3) }
+	s := NewServer("prefork", WithPrefork(1)).(*Server)
+	s.Port = 0
+	s.master.Args = []string{"-test.run=^TestServer_PreforkCrashLoop$"}
+	s.master.RestartDelay = 10 * time.Millisecond
+	s.master.MaxFailures = 3
+
+	done := make(chan struct{})
+	go func() {
+		s.Serve()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("master kept restarting a crashing child")
+	}
+	if err := s.Err(); err == nil || !strings.Contains(err.Error(), "3 times in a row") { t.Fatalf("unexpected err %v", err) }
+	if pids := s.master.Pids(); len(pids) != 0 { t.Fatalf("children left running: %v", pids) }
+}
+
+func TestServer_GracefulRestart(t *testing.T) {
+	child := os.Getenv(graceful.EnvInheritFDs) != ""
+	s := NewServer("graceful", WithGracefulRestart()).(*Server)
+	s.Port = 0
+	if child {
+		s.Port, _ = strconv.Atoi(os.Getenv("ZNET_RESTART_TEST_PORT"))
+	}
+	release := make(chan struct{})
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		if string(req.GetData()) == "slow" { <-release }
+		return req.GetConnection().Send(1, []byte(strconv.Itoa(os.Getpid())))
+	})
+	if child {
+		// 新进程：接管监听后服务一个连接即退出
+		done := make(chan struct{})
+		var once sync.Once
+		s.SetOnConnStop(func(ziface.IConnection) { once.Do(func() { close(done) }) })
+		close(release)
+		s.Start()
+		if s.Addr().(*net.TCPAddr).Port != s.Port { t.Fatalf("listener not inherited") }
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+		}
+		s.Stop(context.Background())
 		return
 	}
+
+	if err := s.Restart(context.Background()); err != ErrServerNotRunning { t.Fatalf("expected ErrServerNotRunning, got %v", err) }
+	s.Start()
+	addr := s.Addr().String()
+	t.Setenv("ZNET_RESTART_TEST_PORT", strconv.Itoa(s.Addr().(*net.TCPAddr).Port))
+	s.restarter.Args = []string{"-test.run=^TestServer_GracefulRestart$"}
+
+	// 重启前发出的慢请求应由旧进程处理完毕
+	old, err := net.Dial("tcp", addr)
+	if err != nil { t.Fatal(err) }
+	defer old.Close()
+	writeMsg(t, old, 1, []byte("slow"))
+	waitFor(t, func() bool { return s.connMgr.Len() == 1 })
+
+	restarted := make(chan error, 1)
+	go func() { restarted <- s.Restart(context.Background()) }()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if pid := string(readMsg(t, old).GetData()); pid != strconv.Itoa(os.Getpid()) { t.Fatalf("in-flight request served by %s", pid) }
+	select {
+	case err := <-restarted:
+		if err != nil { t.Fatal(err) }
+	case <-time.After(10 * time.Second):
+		t.Fatalf("restart did not finish")
+	}
+	select {
+	case <-s.exit:
+	default:
+		t.Fatalf("old server should be stopped after restart")
+	}
+
+	// 新连接由新进程处理
+	conn, err := net.Dial("tcp", addr)
+	if err != nil { t.Fatal(err) }
 	defer conn.Close()
+	writeMsg(t, conn, 1, nil)
+	if pid := string(readMsg(t, conn).GetData()); pid == strconv.Itoa(os.Getpid()) { t.Fatalf("request served by old process") }
+}
+
+func TestServer_PooledBuffers(t *testing.T) {
+	for _, tr := range []Transport{TransportGoroutine, TransportEpoll} {
+		if tr == TransportEpoll && runtime.GOOS != "linux" { continue }
+		s := newTestServer(t, WithTransport(tr, 2), WithWorkerPool(2, 64), WithMaxPacketSize(64<<10))
+		// 处理函数返回后数据归还缓冲池，回显前先校验内容未被其它消息覆盖
+		s.AddRouter(1, func(req ziface.IRequest) error {
+			data := req.GetData()
+			for _, b := range data[1:] {
+				if b != data[0] { return req.GetConnection().Send(2, nil) }
+			}
+			return req.GetConnection().SendBuffered(1, data)
+		})
+		s.Start()
+		before := zpool.ReadStats()
+
+		// 多个连接交错收发，使不同连接的消息同时占用池化缓冲区
+		conns := make([]net.Conn, 4)
+		for c := range conns {
+			conn, err := net.Dial("tcp", s.Addr().String())
+			if err != nil { t.Fatal(err) }
+			defer conn.Close()
+			conns[c] = conn
+		}
+		for i := 0; i < 50; i++ {
+			payloads := make([][]byte, len(conns))
+			for c, conn := range conns {
+				payloads[c] = bytes.Repeat([]byte{byte(c*50 + i)}, 1+(i*997+c*131)%(32<<10))
+				writeMsg(t, conn, 1, payloads[c])
+			}
+			for c, conn := range conns {
+				msg := readMsg(t, conn)
+				if msg.GetMsgID() != 1 || !bytes.Equal(msg.GetData(), payloads[c]) { t.Fatalf("%v: corrupted echo for msg %d", tr, i) }
+			}
+		}
+		_ = s.Stop(context.Background())
+		if after := zpool.ReadStats(); after.Puts-before.Puts < 400 { t.Fatalf("%v: expected buffers returned to pool, puts %d", tr, after.Puts-before.Puts) }
+	}
+}
+
+type typedReq struct {
+	ID   int64    `pb:"1" json:"id"`
+	Name string   `pb:"2" json:"name"`
+	Tags []string `pb:"3" json:"tags"`
+}
+
+type typedResp struct {
+	Greeting string `pb:"1" json:"greeting"`
+	Count    int32  `pb:"2,zigzag" json:"count"`
+}
+
+func TestServer_TypedCodec(t *testing.T) {
+	for _, name := range []string{"json", "gob", "proto"} {
+		codec, err := zcodec.Get(name)
+		if err != nil { t.Fatal(err) }
+		s := newTestServer(t, WithCodec(codec))
+		failed := make(chan error, 1)
+		s.AddRouter(1, Typed(func(req ziface.IRequest, msg *typedReq) error {
+			return req.Reply(2, &typedResp{Greeting: "hello " + msg.Name, Count: -int32(len(msg.Tags))})
+		}))
+		s.AddRouter(3, func(req ziface.IRequest) error {
+			err := Typed(func(ziface.IRequest, *typedReq) error { return nil })(req)
+			failed <- err
+			return err
+		})
+		s.Start()
+		if s.Codec().Name() != name { t.Fatalf("codec not applied: %s", s.Codec().Name()) }
+
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil { t.Fatal(err) }
+		data, err := codec.Encode(&typedReq{ID: 7, Name: name, Tags: []string{"a", "b"}})
+		if err != nil { t.Fatal(err) }
+		writeMsg(t, conn, 1, data)
+		msg := readMsg(t, conn)
+		var resp typedResp
+		if err := codec.Decode(msg.GetData(), &resp); err != nil { t.Fatalf("%s: decode reply: %v", name, err) }
+		if msg.GetMsgID() != 2 || resp.Greeting != "hello "+name || resp.Count != -2 { t.Fatalf("%s: unexpected reply %d %+v", name, msg.GetMsgID(), resp) }
+
+		// 无法解码的数据不会调用处理函数
+		writeMsg(t, conn, 3, []byte{0xff, 0xff, 0xff})
+		select {
+		case err := <-failed:
+			if err == nil { t.Fatalf("%s: expected decode error", name) }
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s: handler not called", name)
+		}
+		conn.Close()
+		_ = s.Stop(context.Background())
+	}
+}
+
+func TestClient_Echo(t *testing.T) {
+	s := newTestServer(t)
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		return req.GetConnection().Send(2, req.GetData())
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	replies := make(chan string, 1)
+	c := NewClient("client", "tcp", s.Addr().String())
+	c.AddRouter(2, func(req ziface.IRequest) error {
+		replies <- string(req.GetData())
+		return nil
+	})
+	if err := c.Send(1, nil); !errors.Is(err, ErrNotConnected) { t.Fatalf("expected ErrNotConnected, got %v", err) }
+	c.Start()
+	defer c.Stop(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := c.WaitConnected(ctx); err != nil { t.Fatal(err) }
+	if err := c.SendBuffered(1, []byte("hello")); err != nil { t.Fatal(err) }
+	select {
+	case got := <-replies:
+		if got != "hello" { t.Fatalf("unexpected reply %q", got) }
+	case <-time.After(3 * time.Second):
+		t.Fatalf("reply not received")
+	}
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	s := newTestServer(t)
+	s.Start()
+	addr := s.Addr().String()
+
+	var connects, disconnects atomic.Int32
+	c := NewClient("client", "tcp", addr,
+		WithBackoff(BackoffConfig{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond}),
+		WithOnConnect(func(ziface.IConnection) { connects.Add(1) }),
+		WithOnDisconnect(func(ziface.IConnection) { disconnects.Add(1) }),
+	)
+	c.Start()
+	defer c.Stop(context.Background())
+	waitFor(t, func() bool { return connects.Load() == 1 })
+
+	// 服务端停止后客户端持续重试，同一端口重新启动后自动连上
+	_ = s.Stop(context.Background())
+	waitFor(t, func() bool { return disconnects.Load() == 1 && c.Conn() == nil })
+	if err := c.Send(1, nil); !errors.Is(err, ErrNotConnected) { t.Fatalf("expected ErrNotConnected, got %v", err) }
+	_, port, _ := net.SplitHostPort(addr)
+	s2 := newTestServer(t)
+	s2.Port, _ = strconv.Atoi(port)
+	s2.Start()
+	defer s2.Stop(context.Background())
+	waitFor(t, func() bool { return connects.Load() == 2 && c.Conn() != nil })
+	waitFor(t, func() bool { return s2.GetConnMgr().Len() == 1 })
+}
 
-	for {
-		_, err := conn.Write([]byte("hello world"))
-		if err != nil {
-			fmt.Printf("[ERROR]Write failed, err: %v\n", err)
-			continue
+func TestClient_ReconnectBackoff(t *testing.T) {
+	// 对端接受后立即关闭：重连间隔应按退避增长，而不是固定的首次等待时间
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil { t.Fatal(err) }
+	defer l.Close()
+	accepts := make(chan time.Time, 16)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil { return }
+			accepts <- time.Now()
+			conn.Close()
 		}
-		buf := make([]byte, 512)
-		n, err := conn.Read(buf)
-		if err != nil {
-			fmt.Printf("[ERROR]Read failed, err: %v\n", err)
-			continue
+	}()
+	c := NewClient("client", "tcp", l.Addr().String(),
+		WithBackoff(BackoffConfig{Initial: 20 * time.Millisecond, Max: time.Second, Jitter: 0.01, Stable: time.Second}))
+	c.Start()
+	defer c.Stop(context.Background())
+
+	var times []time.Time
+	for len(times) < 5 {
+		select {
+		case at := <-accepts:
+			times = append(times, at)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("only %d reconnects", len(times))
 		}
-		fmt.Printf("[RECV]%s\n", buf[:n])
+	}
+	for i := 2; i < len(times); i++ {
+		prev, gap := times[i-1].Sub(times[i-2]), times[i].Sub(times[i-1])
+		if gap < prev*3/2 { t.Fatalf("reconnect gap did not grow: %v then %v", prev, gap) }
 	}
 }
 
-func TestServer(t *testing.T) {
-	s := NewServer("test")
-	go ClientTest()
-	s.Serve()
-}
\ No newline at end of file
+func TestClient_Backoff(t *testing.T) {
+	b := BackoffConfig{Initial: 10 * time.Millisecond, Max: 100 * time.Millisecond, Multiplier: 2, Jitter: 0.5}
+	for attempt, want := range []time.Duration{10, 20, 40, 80, 100, 100} {
+		want *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if d := b.delay(attempt); d > want || d < want/2 { t.Fatalf("attempt %d: delay %v out of [%v, %v]", attempt, d, want/2, want) }
+		}
+	}
+
+	// 取一个已关闭的端口，拨号必然失败
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil { t.Fatal(err) }
+	addr := l.Addr().String()
+	l.Close()
+
+	type failure struct {
+		attempt int
+		wait    time.Duration
+	}
+	failures := make(chan failure, 16)
+	c := NewClient("client", "tcp", addr,
+		WithBackoff(BackoffConfig{Initial: 5 * time.Millisecond, Max: 20 * time.Millisecond, Jitter: 0.5}),
+		WithOnDialError(func(err error, attempt int, wait time.Duration) {
+			select {
+			case failures <- failure{attempt, wait}:
+			default:
+			}
+		}),
+	)
+	c.Start()
+	for i, want := range []time.Duration{5, 10, 20, 20} {
+		want *= time.Millisecond
+		select {
+		case f := <-failures:
+			if f.attempt != i+1 { t.Fatalf("expected attempt %d, got %d", i+1, f.attempt) }
+			if f.wait > want || f.wait < want/2 { t.Fatalf("attempt %d: wait %v out of [%v, %v]", f.attempt, f.wait, want/2, want) }
+		case <-time.After(3 * time.Second):
+			t.Fatalf("dial error %d not reported", i+1)
+		}
+	}
+	if err := c.Stop(context.Background()); err != nil { t.Fatal(err) }
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.WaitConnected(ctx); !errors.Is(err, ErrNotConnected) { t.Fatalf("expected ErrNotConnected after Stop, got %v", err) }
+}
+
+func TestClient_Heartbeat(t *testing.T) {
+	// 服务端只做空闲检测，客户端主动 ping，连接应一直存活
+	s := newTestServer(t, WithHeartbeat(HeartbeatConfig{Interval: 20 * time.Millisecond, Timeout: 80 * time.Millisecond}))
+	s.Start()
+	defer s.Stop(context.Background())
+
+	var disconnects atomic.Int32
+	c := NewClient("client", "tcp", s.Addr().String(),
+		WithConnOptions(WithHeartbeat(HeartbeatConfig{Interval: 20 * time.Millisecond, Timeout: 200 * time.Millisecond, SendPing: true})),
+		WithOnDisconnect(func(ziface.IConnection) { disconnects.Add(1) }),
+	)
+	c.Start()
+	defer c.Stop(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	conn, err := c.WaitConnected(ctx)
+	if err != nil { t.Fatal(err) }
+	time.Sleep(400 * time.Millisecond)
+	if disconnects.Load() != 0 || c.Conn() != conn { t.Fatalf("client conn dropped despite heartbeat") }
+	if s.GetConnMgr().Len() != 1 { t.Fatalf("server should keep the conn, have %d", s.GetConnMgr().Len()) }
+}
+
+// testCert 由 parent 签发（parent 为 nil 时自签为 CA）的 ECDSA 证书
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil { t.Fatal(err) }
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil { t.Fatal(err) }
+	cert, err := x509.ParseCertificate(der)
+	if err != nil { t.Fatal(err) }
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+// writePEM 将证书与私钥写入 dir，返回文件路径
+func (c *testCert) writePEM(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil { t.Fatal(err) }
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600); err != nil { t.Fatal(err) }
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil { t.Fatal(err) }
+	return certFile, keyFile
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	ca := newTestCert(t, "test-ca", nil)
+	serverCert := newTestCert(t, "server", ca)
+	device := newTestCert(t, "device-42", ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	// 处理函数按客户端证书的 CN 鉴别设备身份
+	s := newTestServer(t, WithTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert.tls()},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}), WithTLSHandshakeTimeout(time.Second))
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		cert := req.GetConnection().PeerCertificate()
+		if cert == nil {
+			return req.GetConnection().Send(2, []byte("anonymous"))
+		}
+		return req.GetConnection().Send(2, []byte(cert.Subject.CommonName))
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	replies := make(chan string, 1)
+	c := NewClient("device", "tcp", s.Addr().String(), WithClientTLS(&tls.Config{
+		Certificates: []tls.Certificate{device.tls()},
+		RootCAs:      pool,
+	}))
+	c.AddRouter(2, func(req ziface.IRequest) error {
+		replies <- string(req.GetData())
+		return nil
+	})
+	c.Start()
+	defer c.Stop(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	conn, err := c.WaitConnected(ctx)
+	if err != nil { t.Fatal(err) }
+	if cert := conn.PeerCertificate(); cert == nil || cert.Subject.CommonName != "server" { t.Fatalf("client should see verified server cert, got %v", cert) }
+	if err := c.Send(1, nil); err != nil { t.Fatal(err) }
+	select {
+	case got := <-replies:
+		if got != "device-42" { t.Fatalf("unexpected identity %q", got) }
+	case <-time.After(3 * time.Second):
+		t.Fatalf("reply not received")
+	}
+
+	// 未出示证书、证书非受信 CA 签发、明文连接均在握手阶段被拒绝
+	rogueCA := newTestCert(t, "rogue-ca", nil)
+	for name, cfg := range map[string]*tls.Config{
+		"no cert":   {RootCAs: pool},
+		"untrusted":  {RootCAs: pool, Certificates: []tls.Certificate{newTestCert(t, "device-43", rogueCA).tls()}},
+	} {
+		tc, err := tls.Dial("tcp", s.Addr().String(), cfg)
+		if err == nil {
+			// TLS 1.3 下客户端证书校验失败在首次读时才暴露
+			_ = tc.SetReadDeadline(time.Now().Add(3 * time.Second))
+			_, err = tc.Read(make([]byte, 1))
+			tc.Close()
+		}
+		if err == nil || errors.Is(err, os.ErrDeadlineExceeded) { t.Fatalf("%s: expected handshake failure, got %v", name, err) }
+	}
+	plain, err := net.Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer plain.Close()
+	writeMsg(t, plain, 1, nil)
+	_ = plain.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := io.ReadAll(plain); err != nil { t.Fatalf("plaintext conn should be closed, got %v", err) }
+	if s.GetConnMgr().Len() != 1 { t.Fatalf("only the authenticated conn should be registered, have %d", s.GetConnMgr().Len()) }
+}
+
+func TestServer_TLSConfigFiles(t *testing.T) {
+	ca := newTestCert(t, "test-ca", nil)
+	dir := t.TempDir()
+	certFile, keyFile := newTestCert(t, "server", ca).writePEM(t, dir, "server")
+
+	cfg := zconfig.Default()
+	cfg.Port = 0
+	cfg.Transport = "epoll"
+	cfg.TLS = zconfig.TLSConfig{CertFile: certFile, KeyFile: keyFile}
+	if err := cfg.Validate(); err != nil { t.Fatal(err) }
+	s := NewServerWithConfig(cfg).(*Server)
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		if req.GetConnection().PeerCertificate() != nil { return req.GetConnection().Send(2, []byte("cert")) }
+		return req.GetConnection().Send(2, req.GetData())
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+	if s.poller != nil { t.Fatalf("epoll transport should fall back under TLS") }
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	conn, err := tls.Dial("tcp", s.Addr().String(), &tls.Config{RootCAs: pool})
+	if err != nil { t.Fatal(err) }
+	defer conn.Close()
+	writeMsg(t, conn, 1, []byte("secret"))
+	if msg := readMsg(t, conn); msg.GetMsgID() != 2 || string(msg.GetData()) != "secret" {
+		t.Fatalf("unexpected reply: id=%d data=%q", msg.GetMsgID(), msg.GetData())
+	}
+
+	// 证书文件缺失时 Start 失败而不是退化为明文
+	cfg.TLS.KeyFile = filepath.Join(dir, "missing.key")
+	bad := NewServerWithConfig(cfg).(*Server)
+	bad.Start()
+	if bad.Addr() != nil { t.Fatalf("server should not listen without a usable certificate") }
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	for _, transport := range []Transport{TransportGoroutine, TransportEpoll} {
+		path := filepath.Join(t.TempDir(), "zinx.sock")
+		// 模拟上次进程异常退出遗留的 socket 文件
+		stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+		if err != nil { t.Fatal(err) }
+		stale.SetUnlinkOnClose(false)
+		stale.Close()
+		if _, err := os.Stat(path); err != nil { t.Fatalf("stale socket should exist: %v", err) }
+
+		s := NewServer("unix", WithUnixSocket(path, 0o600), WithTransport(transport, 1)).(*Server)
+		s.AddRouter(1, func(req ziface.IRequest) error {
+			return req.GetConnection().Send(2, req.GetData())
+		})
+		s.Start()
+		if s.Addr() == nil { t.Fatalf("server should listen on %s", path) }
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 { t.Fatalf("socket perm: %v %v", fi.Mode(), err) }
+
+		replies := make(chan string, 1)
+		c := NewClient("sidecar", "unix", path)
+		c.AddRouter(2, func(req ziface.IRequest) error {
+			replies <- string(req.GetData())
+			return nil
+		})
+		c.Start()
+		if err := func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			_, err := c.WaitConnected(ctx)
+			return err
+		}(); err != nil { t.Fatal(err) }
+		if err := c.Send(1, []byte("ping")); err != nil { t.Fatal(err) }
+		select {
+		case got := <-replies:
+			if got != "ping" { t.Fatalf("unexpected reply %q", got) }
+		case <-time.After(3 * time.Second):
+			t.Fatalf("reply not received")
+		}
+
+		// 仍在监听的 socket 不会被当作残留文件删除
+		busy := NewServer("unix", WithUnixSocket(path, 0)).(*Server)
+		busy.Start()
+		if busy.Addr() != nil { t.Fatalf("second server should not take over a live socket") }
+
+		_ = c.Stop(context.Background())
+		_ = s.Stop(context.Background())
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) { t.Fatalf("socket file should be removed on stop, got %v", err) }
+	}
+
+	// 同名的普通文件不会被删除
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("keep"), 0o600); err != nil { t.Fatal(err) }
+	s := NewServer("unix", WithUnixSocket(path, 0)).(*Server)
+	s.Start()
+	if s.Addr() != nil { t.Fatalf("server should refuse a non-socket path") }
+	if b, err := os.ReadFile(path); err != nil || string(b) != "keep" { t.Fatalf("regular file touched: %q %v", b, err) }
+}
+
+// udpRoundTrip 发送一个数据报并读出一个回复帧
+func udpRoundTrip(t *testing.T, conn net.Conn, datagram []byte) ziface.IMessage {
+	t.Helper()
+	if _, err := conn.Write(datagram); err != nil { t.Fatal(err) }
+	return readUDP(t, conn)
+}
+
+func readUDP(t *testing.T, conn net.Conn) ziface.IMessage {
+	t.Helper()
+	dp := NewDataPack()
+	buf := make([]byte, maxDatagramSize)
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil { t.Fatalf("read datagram: %v", err) }
+	msg, err := dp.Unpack(buf[:dp.GetHeadLen()])
+	if err != nil { t.Fatal(err) }
+	if int(dp.GetHeadLen()+msg.GetDataLen()) != n { t.Fatalf("datagram carries %d bytes, frame wants %d", n, dp.GetHeadLen()+msg.GetDataLen()) }
+	msg.SetData(buf[dp.GetHeadLen():n])
+	return msg
+}
+
+func TestServer_UDP(t *testing.T) {
+	var stopped atomic.Int32
+	s := newTestServer(t, WithUDP(150*time.Millisecond))
+	s.SetOnConnStop(func(ziface.IConnection) { stopped.Add(1) })
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		reply := fmt.Sprintf("%d:%s", req.GetConnection().GetConnID(), req.GetData())
+		return req.GetConnection().Send(2, []byte(reply))
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+	if _, ok := s.Addr().(*net.UDPAddr); !ok { t.Fatalf("expected udp addr, got %v", s.Addr()) }
+
+	frame := func(id uint32, data string) []byte {
+		buf, err := NewDataPack().Pack(NewMessage(id, []byte(data)))
+		if err != nil { t.Fatal(err) }
+		return append([]byte(nil), buf...)
+	}
+	a, err := net.Dial("udp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer a.Close()
+	b, err := net.Dial("udp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer b.Close()
+
+	// 一个数据报可携带多帧，同一对端的帧归入同一会话并按序处理
+	if _, err := a.Write(append(frame(1, "x"), frame(1, "y")...)); err != nil { t.Fatal(err) }
+	first, second := string(readUDP(t, a).GetData()), string(readUDP(t, a).GetData())
+	idA, _, _ := strings.Cut(first, ":")
+	if first != idA+":x" || second != idA+":y" { t.Fatalf("unexpected replies %q %q", first, second) }
+	if got := string(udpRoundTrip(t, b, frame(1, "z")).GetData()); got == idA+":z" || !strings.HasSuffix(got, ":z") { t.Fatalf("peer b should have its own session, got %q", got) }
+	if got := string(udpRoundTrip(t, a, frame(1, "again")).GetData()); got != idA+":again" { t.Fatalf("peer a session changed: %q", got) }
+	if s.GetConnMgr().Len() != 2 { t.Fatalf("expected 2 sessions, have %d", s.GetConnMgr().Len()) }
+
+	// 截断的数据报按畸形帧处理，默认关闭该会话
+	if _, err := a.Write(frame(1, "truncated")[:10]); err != nil { t.Fatal(err) }
+	waitFor(t, func() bool { return s.ProtocolStats().Malformed == 1 && stopped.Load() == 1 })
+
+	// 空闲会话超时后回收，之后的数据报创建新会话
+	waitFor(t, func() bool { return s.GetConnMgr().Len() == 0 && stopped.Load() == 2 })
+	if got := string(udpRoundTrip(t, a, frame(1, "new")).GetData()); got == idA+":new" || !strings.HasSuffix(got, ":new") { t.Fatalf("expected a fresh session, got %q", got) }
+
+	// 新对端的首个数据报不是合法帧时直接丢弃，不创建会话
+	c, err := net.Dial("udp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+	if _, err := c.Write([]byte("garbage")); err != nil { t.Fatal(err) }
+	if !strings.HasSuffix(string(udpRoundTrip(t, c, frame(1, "ok")).GetData()), ":ok") { t.Fatalf("valid frame after junk not served") }
+	if s.GetConnMgr().Len() != 2 || stopped.Load() != 2 || s.ProtocolStats().Malformed != 1 { t.Fatalf("junk datagram created a session: %d %d %+v", s.GetConnMgr().Len(), stopped.Load(), s.ProtocolStats()) }
+}
+
+func TestServer_UDPSlowConnStart(t *testing.T) {
+	// 未启用工作池时处理函数会运行在共用的读循环中，拒绝启动
+	inline := newTestServer(t, WithUDP(0), WithWorkerPool(0, 0))
+	inline.Start()
+	if inline.Addr() != nil { t.Fatalf("udp without worker pool should not start") }
+
+	s := newTestServer(t, WithUDP(0))
+	release := make(chan struct{})
+	var first atomic.Bool
+	// 第一个对端的 OnConnStart 阻塞，期间其它对端不受影响
+	s.SetOnConnStart(func(ziface.IConnection) {
+		if first.CompareAndSwap(false, true) { <-release }
+	})
+	s.AddRouter(1, func(req ziface.IRequest) error { return req.GetConnection().Send(2, req.GetData()) })
+	s.Start()
+	defer s.Stop(context.Background())
+
+	frame := func(data string) []byte {
+		buf, _ := NewDataPack().Pack(NewMessage(1, []byte(data)))
+		return append([]byte(nil), buf...)
+	}
+	a, err := net.Dial("udp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer a.Close()
+	if _, err := a.Write(frame("a")); err != nil { t.Fatal(err) }
+	waitFor(t, first.Load)
+	b, err := net.Dial("udp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer b.Close()
+	if got := string(udpRoundTrip(t, b, frame("b")).GetData()); got != "b" { t.Fatalf("unexpected reply %q", got) }
+	// OnConnStart 返回后处理首个数据报
+	close(release)
+	if got := string(readUDP(t, a).GetData()); got != "a" { t.Fatalf("unexpected reply %q", got) }
+}
+
+func TestServer_UDPWorkerBusy(t *testing.T) {
+	s := newTestServer(t, WithUDP(0), WithWorkerPool(1, 1), WithOverloadPolicy(PolicyBlock))
+	started := make(chan struct{}, 4)
+	s.SetOnConnStart(func(ziface.IConnection) { started <- struct{}{} })
+	release := make(chan struct{})
+	s.AddRouter(1, func(req ziface.IRequest) error {
+		<-release
+		return nil
+	})
+	s.Start()
+	defer s.Stop(context.Background())
+	defer close(release)
+
+	a, err := net.Dial("udp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer a.Close()
+	frame, _ := NewDataPack().Pack(NewMessage(1, []byte("x")))
+	// 占住唯一的 worker 并填满队列后继续发送，读循环不能被 PolicyBlock 阻塞
+	for i := 0; i < 4; i++ {
+		if _, err := a.Write(frame); err != nil { t.Fatal(err) }
+	}
+	<-started
+	b, err := net.Dial("udp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer b.Close()
+	if _, err := b.Write(frame); err != nil { t.Fatal(err) }
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("read loop blocked by a full worker queue")
+	}
+}
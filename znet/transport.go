@@ -0,0 +1,60 @@
+package znet
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+// Transport 连接的 I/O 模型
+type Transport uint8
+
+const (
+	// TransportGoroutine 每条连接一个读协程和一个写协程（默认）
+	TransportGoroutine Transport = iota
+	// TransportEpoll 多 Reactor 事件循环：每个循环用 epoll 管理一组非阻塞连接，
+	// 空闲连接不占用 goroutine 栈，仅支持 Linux，其它平台启动时回退到 TransportGoroutine。
+	//
+	// 与 TransportGoroutine 的差异：
+	//   - IConnection.GetConn 返回 nil，读超时不生效，空闲检测请使用心跳；
+	//   - 发送队列满时 OverflowBlock 按 OverflowDrop 处理，未启用工作池时 Send 也不等待数据写入 socket，
+	//     因为此时处理函数运行在事件循环中，阻塞会拖住同一循环上的所有连接；
+	//   - 工作池队列满时 PolicyBlock 按 PolicyDrop 处理（丢弃消息），因为阻塞会拖住整个事件循环；
+	//     需要通知客户端时请使用 PolicyReject。
+	TransportEpoll
+)
+
+// ParseTransport 将配置中的名称转换为 Transport，空字符串表示默认值
+func ParseTransport(name string) (Transport, error) {
+	switch name {
+	case "", "goroutine":
+		return TransportGoroutine, nil
+	case "epoll":
+		return TransportEpoll, nil
+	default:
+		return 0, fmt.Errorf("znet: unknown transport %q", name)
+	}
+}
+
+// poller 事件循环传输的内部抽象，由平台相关文件实现
+type poller interface {
+	// add 接管一条已完成 OnAccept 的 TCP 或 unix socket 连接
+	add(conn net.Conn, connID uint64) (ziface.IConnection, error)
+	// quiesce 等待各事件循环处理完已取到的事件，之后停止读取的连接不会再分发消息
+	quiesce()
+	// stop 停止全部事件循环
+	stop()
+}
+
+// WithTransport 选择连接 I/O 模型，loops 为事件循环数量，<= 0 时取 GOMAXPROCS
+func WithTransport(t Transport, loops int) Option {
+	return func(s *Server) {
+		s.transport = t
+		if loops <= 0 {
+			loops = runtime.GOMAXPROCS(0)
+		}
+		s.eventLoops = loops
+	}
+}
@@ -0,0 +1,23 @@
+package znet
+
+import (
+	"fmt"
+
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+// Typed 将类型化处理函数包装为 MsgHandler：消息数据按服务器配置的编解码器解码为 T 后再调用 fn，
+// 解码失败时返回错误且不调用 fn
+//
+//	s.AddRouter(1, znet.Typed(func(req ziface.IRequest, msg *LoginReq) error {
+//		return req.Reply(1, &LoginResp{Token: auth(msg)})
+//	}))
+func Typed[T any](fn func(req ziface.IRequest, msg *T) error) ziface.MsgHandler {
+	return func(req ziface.IRequest) error {
+		msg := new(T)
+		if err := req.Bind(msg); err != nil {
+			return fmt.Errorf("znet: decode msgID %d: %w", req.GetMsgID(), err)
+		}
+		return fn(req, msg)
+	}
+}
@@ -0,0 +1,285 @@
+package znet
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/zpool"
+	"github.com/SparkleBo/zinx/ztimer"
+)
+
+const (
+	defaultUDPSessionTimeout = time.Minute
+	// maxDatagramSize UDP 数据报的最大长度
+	maxDatagramSize = 64 * 1024
+)
+
+// udpListener 单个 UDP socket 上的读循环与伪会话表：同一对端地址的数据报归入同一会话，
+// 会话在空闲超时后关闭，下次收到该地址的数据报时重新创建
+type udpListener struct {
+	server *Server
+	pc     *net.UDPConn
+
+	mu       sync.Mutex
+	sessions map[netip.AddrPort]*udpSession
+}
+
+func newUDPListener(s *Server, pc *net.UDPConn) *udpListener {
+	return &udpListener{server: s, pc: pc, sessions: make(map[netip.AddrPort]*udpSession)}
+}
+
+// serve 读取数据报并按对端分发，每个数据报须包含一个或多个完整的帧
+func (u *udpListener) serve() {
+	defer u.server.acceptors.Done()
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := u.pc.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if u.server.draining.Load() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			fmt.Printf("[ERROR]Read udp failed, err: %v\n", err)
+			continue
+		}
+		sess := u.session(addr, buf[:n])
+		// OnConnStart 返回前会话尚未就绪，期间到达的数据报被丢弃
+		if sess == nil || !sess.ready.Load() {
+			continue
+		}
+		sess.touch()
+		sess.dispatch(buf[:n])
+	}
+}
+
+// session 返回对端地址对应的会话，不存在时创建；首个数据报不以完整合法帧开头或超过最大连接数时
+// 丢弃数据报并返回 nil。UDP 源地址可以伪造，会话数只受 maxConn 约束，需要鉴权时请在 OnConnStart 中完成。
+// 设置了 OnConnStart 时它在独立 goroutine 中执行，避免慢鉴权拖住全部对端，返回后再处理首个数据报
+func (u *udpListener) session(addr netip.AddrPort, data []byte) *udpSession {
+	s := u.server
+	u.mu.Lock()
+	if sess, ok := u.sessions[addr]; ok {
+		u.mu.Unlock()
+		return sess
+	}
+	if err := u.admit(data); err != nil {
+		u.mu.Unlock()
+		fmt.Printf("[WARN]Drop datagram from unknown peer %s, err: %v\n", addr, err)
+		return nil
+	}
+	if s.maxConn > 0 && s.connMgr.Len() >= s.maxConn {
+		u.mu.Unlock()
+		fmt.Printf("[WARN]Too many connections, max: %d, drop datagram from %s\n", s.maxConn, addr)
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sess := &udpSession{
+		listener: u,
+		addr:     addr,
+		remote:   net.UDPAddrFromAddrPort(addr),
+		connID:   s.connID.Add(1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	u.sessions[addr] = sess
+	u.mu.Unlock()
+
+	sess.touch()
+	s.connMgr.Add(sess)
+	// 启用心跳时由心跳配置决定空闲超时，否则按会话超时回收
+	if hb := s.heartbeat; hb != nil {
+		hb.watch(sess)
+	} else {
+		sess.watchIdle(ztimer.Default(), s.udpTimeout, sess.Close)
+	}
+	fn := s.onConnStart
+	if fn == nil {
+		sess.ready.Store(true)
+		return sess
+	}
+	// 读缓冲区会被下一个数据报覆盖，首个数据报须拷贝；计入 acceptors 使 Stop 等待其分发完毕
+	first := append([]byte(nil), data...)
+	s.acceptors.Add(1)
+	go func() {
+		defer s.acceptors.Done()
+		fn(sess)
+		if sess.ctx.Err() == nil {
+			sess.dispatch(first)
+		}
+		sess.ready.Store(true)
+	}()
+	return sess
+}
+
+// admit 检查新对端的首个数据报以一个完整且不超限的帧开头，随机数据不会占用会话与工作池
+func (u *udpListener) admit(data []byte) error {
+	s := u.server
+	headLen := int(s.packet.GetHeadLen())
+	if len(data) < headLen {
+		return malformed(fmt.Errorf("truncated head, %d bytes", len(data)))
+	}
+	msg, err := s.packet.Unpack(data[:headLen])
+	if err != nil {
+		return malformed(err)
+	}
+	if limit := s.maxPacketSize; limit > 0 && msg.GetDataLen() > limit {
+		return oversize(msg, limit)
+	}
+	if len(data) < headLen+int(msg.GetDataLen()) {
+		return malformed(fmt.Errorf("truncated data, want %d bytes, have %d", msg.GetDataLen(), len(data)-headLen))
+	}
+	return nil
+}
+
+// stopReading 打断阻塞中的读，读循环随即退出；socket 保持打开，在途消息仍可回复
+func (u *udpListener) stopReading() { _ = u.pc.SetReadDeadline(time.Now()) }
+
+// udpSession UDP 伪会话：以对端地址标识，发送直接写数据报，不经过发送队列
+type udpSession struct {
+	listener *udpListener
+	addr     netip.AddrPort
+	remote   net.Addr
+	connID   uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+	// ready OnConnStart 返回后置位，之前到达的数据报不分发
+	ready atomic.Bool
+
+	activity
+	properties
+}
+
+// dispatch 拆出数据报中的全部帧交给消息路由；帧不完整时丢弃数据报剩余部分并按协议错误处理
+func (c *udpSession) dispatch(data []byte) {
+	s := c.listener.server
+	packet := s.packet
+	headLen := int(packet.GetHeadLen())
+	for len(data) > 0 {
+		if len(data) < headLen {
+			c.protocolError(malformed(fmt.Errorf("truncated head, %d bytes", len(data))))
+			return
+		}
+		msg, err := packet.Unpack(data[:headLen])
+		if err != nil {
+			c.protocolError(malformed(err))
+			return
+		}
+		if limit := s.maxPacketSize; limit > 0 && msg.GetDataLen() > limit {
+			c.protocolError(oversize(msg, limit))
+			return
+		}
+		total := headLen + int(msg.GetDataLen())
+		if len(data) < total {
+			c.protocolError(malformed(fmt.Errorf("truncated data, want %d bytes, have %d", msg.GetDataLen(), len(data)-headLen)))
+			return
+		}
+		// 读缓冲区会被下一个数据报覆盖，数据须拷贝后再交给工作池
+		req := &Request{conn: c, msg: msg, codec: s.codec}
+		if msg.GetDataLen() > 0 {
+			body := zpool.Get(int(msg.GetDataLen()))
+			copy(body, data[headLen:total])
+			msg.SetData(body)
+			req.pooled = true
+		}
+		data = data[total:]
+		if hb := s.heartbeat; hb != nil && msg.GetMsgID() == hb.cfg.MsgID {
+			if !hb.cfg.SendPing {
+				_ = c.Send(hb.cfg.MsgID, nil)
+			}
+			req.release()
+			continue
+		}
+		// 读循环由全部会话共享，队列满时不能阻塞
+		if err := s.trySendToTaskQueue(req); err != nil {
+			fmt.Printf("[WARN]Conn %d msgID %d not handled, err: %v\n", c.connID, msg.GetMsgID(), err)
+			req.release()
+		}
+	}
+}
+
+// protocolError 数据报无法重新定位帧边界，Skip 仅回复错误帧并保留会话，其余处理方式同 TCP
+func (c *udpSession) protocolError(err error) {
+	switch c.listener.server.protocolError(c, err) {
+	case ProtocolErrorSkip:
+		_ = c.Send(MsgIDError, []byte(err.Error()))
+	case ProtocolErrorReply:
+		_ = c.Send(MsgIDError, []byte(err.Error()))
+		c.Close()
+	default:
+		c.Close()
+	}
+}
+
+// Start 会话在收到首个数据报时即已开始，无需额外启动
+func (c *udpSession) Start() {}
+
+// Close 从会话表与连接管理器中移除，底层 socket 由全部会话共享，不会关闭
+func (c *udpSession) Close() {
+	c.once.Do(func() {
+		c.cancel()
+		c.unwatch()
+		u := c.listener
+		u.mu.Lock()
+		if u.sessions[c.addr] == c {
+			delete(u.sessions, c.addr)
+		}
+		u.mu.Unlock()
+		u.server.connMgr.Remove(c)
+		u.server.groupMgr.LeaveAll(c)
+		if fn := u.server.onConnStop; fn != nil {
+			fn(c)
+		}
+	})
+}
+
+func (c *udpSession) GetConnID() uint64                  { return c.connID }
+func (c *udpSession) GetConn() net.Conn                  { return nil }
+func (c *udpSession) RemoteAddr() net.Addr               { return c.remote }
+func (c *udpSession) PeerCertificate() *x509.Certificate { return nil }
+func (c *udpSession) Context() context.Context           { return c.ctx }
+
+// Send 封包后直接写出一个数据报
+func (c *udpSession) Send(msgID uint32, data []byte) error {
+	return c.SendMsg(NewMessage(msgID, data))
+}
+
+// SendBuffered UDP 写不会因对端消费慢而阻塞，与 Send 相同
+func (c *udpSession) SendBuffered(msgID uint32, data []byte) error {
+	return c.SendMsg(NewMessage(msgID, data))
+}
+
+func (c *udpSession) SendMsg(msg ziface.IMessage) error {
+	if c.ctx.Err() != nil {
+		return ErrConnClosed
+	}
+	s := c.listener.server
+	buf, err := s.packet.Pack(msg)
+	if err != nil {
+		return err
+	}
+	_, err = c.listener.pc.WriteToUDPAddrPort(buf, c.addr)
+	if s.packPooled {
+		zpool.Put(buf)
+	}
+	return err
+}
+
+// trySendRaw 广播时写出共享的已封包数据，不归还缓冲区
+func (c *udpSession) trySendRaw(buf []byte) bool {
+	if c.ctx.Err() != nil {
+		return false
+	}
+	_, err := c.listener.pc.WriteToUDPAddrPort(buf, c.addr)
+	return err == nil
+}
+
+var _ ziface.IConnection = (*udpSession)(nil)
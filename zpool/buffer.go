@@ -0,0 +1,81 @@
+package zpool
+
+import (
+	"io"
+	"sync"
+)
+
+// Buffer 可增长的池化缓冲区，底层数组从分级缓冲池申请，扩容时归还旧数组
+type Buffer struct {
+	B []byte
+}
+
+var bufferPool = sync.Pool{New: func() any { return new(Buffer) }}
+
+// GetBuffer 从池中取出一个空缓冲区，用完须调用 PutBuffer 归还
+func GetBuffer() *Buffer { return bufferPool.Get().(*Buffer) }
+
+// PutBuffer 归还缓冲区及其底层数组，调用后不得再使用 b 及其 Bytes 的返回值
+func PutBuffer(b *Buffer) {
+	if b.B != nil {
+		Put(b.B)
+		b.B = nil
+	}
+	bufferPool.Put(b)
+}
+
+func (b *Buffer) Len() int       { return len(b.B) }
+func (b *Buffer) Bytes() []byte  { return b.B }
+func (b *Buffer) String() string { return string(b.B) }
+func (b *Buffer) Reset()         { b.B = b.B[:0] }
+
+// Grow 保证还能追加 n 字节而无需再次扩容；超出 MaxSize 后不再池化，按至少翻倍扩容，
+// 避免小块追加时每次都重新分配并拷贝全部内容
+func (b *Buffer) Grow(n int) {
+	if cap(b.B)-len(b.B) >= n {
+		return
+	}
+	var nb []byte
+	if need := len(b.B) + n; need > MaxSize {
+		nb = make([]byte, len(b.B), max(2*cap(b.B), need))
+	} else {
+		nb = Get(need)[:len(b.B)]
+	}
+	copy(nb, b.B)
+	if b.B != nil {
+		Put(b.B)
+	}
+	b.B = nb
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.Grow(len(p))
+	b.B = append(b.B, p...)
+	return len(p), nil
+}
+
+func (b *Buffer) WriteString(s string) (int, error) {
+	b.Grow(len(s))
+	b.B = append(b.B, s...)
+	return len(s), nil
+}
+
+func (b *Buffer) WriteByte(c byte) error {
+	b.Grow(1)
+	b.B = append(b.B, c)
+	return nil
+}
+
+// WriteTo 将缓冲区内容写入 w 并清空缓冲区
+func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(b.B)
+	b.B = b.B[:0]
+	return int64(n), err
+}
+
+var (
+	_ io.Writer       = (*Buffer)(nil)
+	_ io.StringWriter = (*Buffer)(nil)
+	_ io.ByteWriter   = (*Buffer)(nil)
+	_ io.WriterTo     = (*Buffer)(nil)
+)
@@ -0,0 +1,76 @@
+// Package zpool 按大小分级的字节缓冲池：申请长度向上取整到 2 的幂次档位，
+// 每个档位一个 sync.Pool，超出最大档位的申请直接分配且不回收
+package zpool
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	minShift = 6  // 最小档位 64B
+	maxShift = 20 // 最大档位 1MB
+	// MaxSize 可被池化的最大容量
+	MaxSize = 1 << maxShift
+)
+
+// pools 第 i 个池存放容量为 1<<(minShift+i) 的底层数组首地址，归还时不额外分配切片头
+var pools [maxShift - minShift + 1]sync.Pool
+
+// Stats 缓冲池计数，均为进程启动以来的累计值
+type Stats struct {
+	// Gets 申请次数
+	Gets uint64
+	// Puts 成功归还次数，容量不属于任何档位的切片不计入
+	Puts uint64
+	// Allocs 池中无可用缓冲而新分配的次数
+	Allocs uint64
+	// Oversize 超出 MaxSize 直接分配的次数
+	Oversize uint64
+}
+
+var gets, puts, allocs, oversize atomic.Uint64
+
+// ReadStats 返回当前计数快照
+func ReadStats() Stats {
+	return Stats{Gets: gets.Load(), Puts: puts.Load(), Allocs: allocs.Load(), Oversize: oversize.Load()}
+}
+
+// class 返回容纳 n 字节的最小档位下标，n 超出 MaxSize 时返回 -1
+func class(n int) int {
+	if n <= 1<<minShift {
+		return 0
+	}
+	if n > MaxSize {
+		return -1
+	}
+	return bits.Len(uint(n-1)) - minShift
+}
+
+// Get 返回长度为 n 的切片，容量为所在档位大小，内容未清零
+func Get(n int) []byte {
+	gets.Add(1)
+	idx := class(n)
+	if idx < 0 {
+		oversize.Add(1)
+		return make([]byte, n)
+	}
+	size := 1 << (minShift + idx)
+	if p, ok := pools[idx].Get().(*byte); ok {
+		return unsafe.Slice(p, size)[:n]
+	}
+	allocs.Add(1)
+	return make([]byte, n, size)
+}
+
+// Put 归还 Get 得到的切片，调用后不得再使用 b；容量不属于任何档位的切片直接丢弃
+func Put(b []byte) {
+	c := cap(b)
+	if c < 1<<minShift || c > MaxSize || c&(c-1) != 0 {
+		return
+	}
+	puts.Add(1)
+	pools[bits.Len(uint(c))-1-minShift].Put(unsafe.SliceData(b[:1]))
+}
@@ -0,0 +1,82 @@
+package zpool
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGetPut(t *testing.T) {
+	cases := []struct{ n, cap int }{{0, 64}, {1, 64}, {64, 64}, {65, 128}, {4096, 4096}, {4097, 8192}, {MaxSize, MaxSize}}
+	for _, c := range cases {
+		b := Get(c.n)
+		if len(b) != c.n || cap(b) != c.cap { t.Fatalf("Get(%d): len %d cap %d, want cap %d", c.n, len(b), cap(b), c.cap) }
+		Put(b)
+	}
+
+	before := ReadStats()
+	big := Get(MaxSize + 1)
+	if len(big) != MaxSize+1 { t.Fatalf("oversize Get returned len %d", len(big)) }
+	Put(big)
+	Put(make([]byte, 100)) // 容量不属于任何档位
+	after := ReadStats()
+	if after.Oversize != before.Oversize+1 { t.Fatalf("oversize not counted") }
+	if after.Puts != before.Puts { t.Fatalf("foreign slices should not be pooled") }
+}
+
+func TestGetReuse(t *testing.T) {
+	b := Get(1000)
+	b[0] = 42
+	Put(b)
+	// sync.Pool 不保证一定命中，只校验命中时容量与长度正确
+	r := Get(900)
+	if len(r) != 900 || cap(r) != 1024 { t.Fatalf("unexpected len %d cap %d", len(r), cap(r)) }
+	if gets := ReadStats().Gets; gets < 2 { t.Fatalf("gets not counted: %d", gets) }
+}
+
+func TestBuffer(t *testing.T) {
+	buf := GetBuffer()
+	var want bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		_, _ = buf.WriteString("hello ")
+		_ = buf.WriteByte(byte('a' + i%26))
+		_, _ = buf.Write([]byte{'\n'})
+		want.WriteString("hello ")
+		want.WriteByte(byte('a' + i%26))
+		want.WriteByte('\n')
+	}
+	if !bytes.Equal(buf.Bytes(), want.Bytes()) { t.Fatalf("buffer content mismatch") }
+	if c := cap(buf.B); c&(c-1) != 0 { t.Fatalf("buffer cap %d not a size class", c) }
+
+	var out bytes.Buffer
+	n, err := buf.WriteTo(&out)
+	if err != nil || int(n) != want.Len() || buf.Len() != 0 { t.Fatalf("WriteTo: n %d err %v len %d", n, err, buf.Len()) }
+	PutBuffer(buf)
+
+	again := GetBuffer()
+	if again.Len() != 0 { t.Fatalf("pooled buffer not empty") }
+	PutBuffer(again)
+}
+
+func BenchmarkGetPut(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Put(Get(512))
+	}
+}
+
+func TestBuffer_GrowPastMaxSize(t *testing.T) {
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	chunk := bytes.Repeat([]byte("0123456789"), 10)
+	var want bytes.Buffer
+	grows := 0
+	for buf.Len() < 4*MaxSize {
+		c := cap(buf.B)
+		_, _ = buf.Write(chunk)
+		want.Write(chunk)
+		if cap(buf.B) != c { grows++ }
+	}
+	if !bytes.Equal(buf.Bytes(), want.Bytes()) { t.Fatalf("content mismatch") }
+	// 池化档位 64B..1MB 共 15 次扩容，之后按翻倍扩容到 4MB 只需 2 次
+	if grows > 20 { t.Fatalf("buffer grew %d times, growth beyond MaxSize is not geometric", grows) }
+}
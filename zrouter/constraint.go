@@ -0,0 +1,47 @@
+package zrouter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+// segment 解析后的路由段
+type segment struct {
+    kind       nodeKind
+    label      string
+    constraint string            // 参数约束的原始写法，如 int、uuid、[a-z]+
+    accept     func(string) bool // 约束对应的校验函数，无约束时为 nil
+}
+
+// parseSegment 解析路由段：:name、:name<约束>、*、*name 与字面量。
+// 约束为 int、uuid 或正则表达式（整段匹配），正则中不能包含 /
+func parseSegment(s string) (segment, error) {
+    if strings.HasPrefix(s, "*") { return segment{kind: nkWildcard, label: s[1:]}, nil }
+    if !strings.HasPrefix(s, ":") { return segment{kind: nkStatic, label: s}, nil }
+    name, constraint, ok := strings.Cut(s[1:], "<")
+    if !ok { return segment{kind: nkParam, label: name}, nil }
+    if !strings.HasSuffix(constraint, ">") || len(constraint) == 1 {
+        return segment{}, fmt.Errorf("malformed constraint in %s", s)
+    }
+    constraint = constraint[:len(constraint)-1]
+    accept, err := compileConstraint(constraint)
+    if err != nil { return segment{}, fmt.Errorf("invalid constraint in %s: %v", s, err) }
+    return segment{kind: nkParam, label: name, constraint: constraint, accept: accept}, nil
+}
+
+// compileConstraint 内置约束与 Context.ParamInt、ParamUUID 的转换保持一致，满足约束的参数一定能转换成功
+func compileConstraint(c string) (func(string) bool, error) {
+    switch c {
+    case "int":
+        return func(s string) bool { _, err := strconv.Atoi(s); return err == nil }, nil
+    case "uuid":
+        return func(s string) bool { _, err := ziface.ParseUUID(s); return err == nil }, nil
+    }
+    re, err := regexp.Compile("^(?:" + c + ")$")
+    if err != nil { return nil, err }
+    return re.MatchString, nil
+}
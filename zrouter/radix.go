@@ -1,6 +1,8 @@
 package zrouter
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/SparkleBo/zinx/ziface"
@@ -10,16 +12,20 @@ type nodeKind uint8
 
 const (
     nkStatic nodeKind = iota // 字面量段
-    nkParam                  // :param
-    nkWildcard               // *
+    nkParam                  // :param 或 :param<约束>
+    nkWildcard               // * 或 *name，name 非空时余下路径写入同名参数
 )
 
 type node struct {
     kind      nodeKind
-    label     string            // 对于 static/param，存储段内容或参数名
+    label     string            // 对于 static/param/wildcard，存储段内容或参数名
     children  []*node           // 压缩后的子节点，按首字符或种类区分
     handler   ziface.Handler
     mws       []ziface.Middleware
+    route     string            // 注册到该节点的路由，或首个经过该节点的路由，冲突提示用
+    // 参数约束：constraint 为原始写法，accept 为校验函数，无约束时为空
+    constraint string
+    accept     func(string) bool
 }
 
 // Router 使用按段压缩的 Radix/Trie
@@ -31,95 +37,173 @@ type Router struct {
 
 func New() *Router { return &Router{root: &node{kind: nkStatic}} }
 
+// Handle 注册路由。参数段可带约束：:id<int>、:id<uuid> 或 :name<正则>，不满足约束的段不会匹配该参数，
+// 而是继续尝试其它路由。与已有路由冲突时 panic，信息中同时给出新旧两条路由：
+// 相同方法与路径重复注册、同一位置约束相同但参数名不同、同一位置 wildcard 名不同、
+// wildcard 不是最后一段、同一路由内参数重名、约束无法解析
 func (r *Router) Handle(method, path string, h ziface.Handler, mws ...ziface.Middleware) {
     // 将 method 合并进第一段以区分不同方法（避免额外维度）
+    method = strings.ToUpper(method)
     full := joinPath(r.prefix, path)
-    segs := splitPath(full)
+    route := method + " " + full
+    // 先完整校验再插入，冲突时不会在树上留下半截路由
+    segs, err := r.parse(method, splitPath(full), route)
+    if err != nil { panic(err) }
     // 在根下以 method 建子树
-    cur := r.ensureChild(r.root, nkStatic, strings.ToUpper(method))
+    cur := r.ensureChild(r.root, segment{kind: nkStatic, label: method}, route)
     // 逐段插入
-    for i, s := range segs {
-        var kind nodeKind
-        var label string
-        if s == "*" {
-            kind = nkWildcard
-        } else if strings.HasPrefix(s, ":") {
-            kind = nkParam
-            label = s[1:]
-        } else {
-            kind = nkStatic
-            label = s
-        }
-        cur = r.ensureChild(cur, kind, label)
-        // wildcard 必须是最后一段
-        if kind == nkWildcard && i != len(segs)-1 {
-            // 忽略后续段，* 吃掉余下路径
-            break
-        }
+    for _, seg := range segs {
+        cur = r.ensureChild(cur, seg, route)
     }
     cur.handler = h
+    cur.route = route
     cur.mws = append(append([]ziface.Middleware{}, r.mws...), mws...)
 }
 
+// parse 解析各段并沿树检查是否与已注册路由冲突
+func (r *Router) parse(method string, raw []string, route string) ([]segment, error) {
+    names := map[string]bool{}
+    segs := make([]segment, 0, len(raw))
+    cur := r.childBy(r.root, nkStatic, method)
+    for i, s := range raw {
+        seg, err := parseSegment(s)
+        if err != nil { return nil, fmt.Errorf("zrouter: %s: %v", route, err) }
+        if seg.kind == nkWildcard && i != len(raw)-1 {
+            return nil, fmt.Errorf("zrouter: %s: wildcard must be the last segment", route)
+        }
+        if seg.kind == nkParam && seg.label == "" {
+            return nil, fmt.Errorf("zrouter: %s: empty parameter name in segment %d", route, i+1)
+        }
+        if seg.kind != nkStatic && seg.label != "" {
+            if names[seg.label] { return nil, fmt.Errorf("zrouter: %s: duplicate parameter %s", route, s) }
+            names[seg.label] = true
+        }
+        segs = append(segs, seg)
+        // 后续段在树上尚不存在，不会再与已有路由冲突
+        if cur == nil { continue }
+        if seg.kind != nkStatic {
+            for _, c := range cur.children {
+                // 约束不同的参数可以并存，匹配时按约束区分
+                if c.kind == seg.kind && c.constraint == seg.constraint && c.label != seg.label {
+                    return nil, fmt.Errorf("zrouter: %s conflicts with %s: %s and %s at the same position", route, c.route, s, c.segment())
+                }
+            }
+        }
+        cur = r.childOf(cur, seg)
+    }
+    if cur != nil && cur.handler != nil {
+        return nil, fmt.Errorf("zrouter: %s conflicts with %s: duplicate route", route, cur.route)
+    }
+    return segs, nil
+}
+
+// segment 还原节点在路由中的写法，用于冲突提示
+func (n *node) segment() string {
+    switch n.kind {
+    case nkParam:
+        if n.constraint != "" { return ":" + n.label + "<" + n.constraint + ">" }
+        return ":" + n.label
+    case nkWildcard:
+        return "*" + n.label
+    }
+    return n.label
+}
+
 // Group 创建带前缀与中间件的子 Router 
 func (r *Router) Group(prefix string, mws ...ziface.Middleware) ziface.Router {
     return &group{parent: r, prefix: joinPath(r.prefix, prefix), mws: append(append([]ziface.Middleware{}, r.mws...), mws...)}
 }
 
-// Find 根据方法与路径查找处理器与参数
+// Find 根据方法与路径查找处理器与参数。
+//
+// 每一段按 静态 > 带约束的参数 > 参数 > wildcard 的优先级尝试匹配，多个带约束的参数按注册顺序尝试；
+// 不满足约束或某个分支在后续段走不通时回溯，
+// 依次尝试同层优先级更低的分支，因此 /users/new 与 /users/:id/edit 同时注册时
+// /users/new/edit 仍会命中参数路由。
+//
+// wildcard 匹配余下全部路径（可为空），命名 wildcard（如 /static/*filepath）将余下路径原样
+// （不含开头的 /，保留末尾的 /）写入同名参数：/static/css/app.css 得到 filepath=css/app.css，
+// /static/ 与 /static 得到 filepath 为空串。
 func (r *Router) Find(method, path string) (ziface.Handler, map[string]string, []ziface.Middleware, bool) {
-    segs := splitPath(path)
-    params := map[string]string{}
     // 方法维度
-    cur := r.childBy(r.root, nkStatic, strings.ToUpper(method))
-    if cur == nil { return nil, nil, nil, false }
-    for i := 0; i < len(segs); i++ {
-        s := segs[i]
-        // 先尝试静态匹配
-        next := r.childBy(cur, nkStatic, s)
-        if next != nil {
-            cur = next
-            continue
-        }
-        // 其次参数匹配
-        next = r.childByKind(cur, nkParam)
-        if next != nil {
-            params[next.label] = s
-            cur = next
-            continue
-        }
-        // 最后 wildcard
-        next = r.childByKind(cur, nkWildcard)
-        if next != nil {
-            cur = next
-            break
+    root := r.childBy(r.root, nkStatic, strings.ToUpper(method))
+    if root == nil { return nil, nil, nil, false }
+    params := map[string]string{}
+    n := r.match(root, strings.TrimLeft(path, "/"), params)
+    if n == nil { return nil, nil, nil, false }
+    return n.handler, params, n.mws, true
+}
+
+// Allowed 返回 path 能在其下匹配到路由的方法（已排序），没有任何方法匹配时返回 nil；
+// 用于区分 405 与 404 以及生成 Allow 头
+func (r *Router) Allowed(path string) []string {
+    path = strings.TrimLeft(path, "/")
+    var methods []string
+    for _, m := range r.root.children {
+        if r.match(m, path, map[string]string{}) != nil {
+            methods = append(methods, m.label)
         }
-        return nil, nil, nil, false
     }
-    if cur.handler == nil {
-        // 路径完全匹配但无处理器，检查是否 wildcard 叶子有 handler
-        wc := r.childByKind(cur, nkWildcard)
-        if wc != nil && wc.handler != nil {
-            cur = wc
-        } else {
-            return nil, nil, nil, false
+    sort.Strings(methods)
+    return methods
+}
+
+// match 在 n 之下匹配剩余路径 path（不含开头的 /），返回带处理器的节点；参数只在所在分支匹配成功后写入，
+// 回溯时不会残留失败分支的参数
+func (r *Router) match(n *node, path string, params map[string]string) *node {
+    if path == "" {
+        if n.handler != nil { return n }
+        // 路径已耗尽，wildcard 叶子匹配空的剩余路径
+        return r.matchWildcard(n, path, params)
+    }
+    seg, rest, _ := strings.Cut(path, "/")
+    // 先尝试静态匹配
+    if next := r.childBy(n, nkStatic, seg); next != nil {
+        if found := r.match(next, rest, params); found != nil { return found }
+    }
+    // 其次参数匹配，带约束的优先
+    for _, constrained := range [2]bool{true, false} {
+        for _, next := range n.children {
+            if next.kind != nkParam || (next.accept != nil) != constrained { continue }
+            if next.accept != nil && !next.accept(seg) { continue }
+            if found := r.match(next, rest, params); found != nil {
+                params[next.label] = seg
+                return found
+            }
         }
     }
-    return cur.handler, params, cur.mws, true
+    // 最后 wildcard
+    return r.matchWildcard(n, path, params)
+}
+
+// matchWildcard wildcard 子节点吞下余下路径，命名 wildcard 将其写入参数
+func (r *Router) matchWildcard(n *node, path string, params map[string]string) *node {
+    wc := r.childByKind(n, nkWildcard)
+    if wc == nil || wc.handler == nil { return nil }
+    if wc.label != "" { params[wc.label] = path }
+    return wc
 }
 
 // --- helpers ---
 
-func (r *Router) ensureChild(n *node, kind nodeKind, label string) *node {
+func (r *Router) ensureChild(n *node, seg segment, route string) *node {
     // 查找是否已有可复用的子节点
+    if c := r.childOf(n, seg); c != nil {
+        return c
+    }
+    c := &node{kind: seg.kind, label: seg.label, route: route, constraint: seg.constraint, accept: seg.accept}
+    n.children = append(n.children, c)
+    return c
+}
+
+// childOf 查找种类、标签与约束都相同的子节点
+func (r *Router) childOf(n *node, seg segment) *node {
     for _, c := range n.children {
-        if c.kind == kind && c.label == label {
+        if c.kind == seg.kind && c.label == seg.label && c.constraint == seg.constraint {
             return c
         }
     }
-    c := &node{kind: kind, label: label}
-    n.children = append(n.children, c)
-    return c
+    return nil
 }
 
 func (r *Router) childBy(n *node, kind nodeKind, label string) *node {
@@ -177,6 +261,10 @@ func (g *group) Group(prefix string, mws ...ziface.Middleware) ziface.Router {
     return &group{parent: g.parent, prefix: joinPath(g.prefix, prefix), mws: append(append([]ziface.Middleware{}, g.mws...), mws...)}
 }
 
+func (g *group) Allowed(path string) []string {
+    return g.parent.Allowed(joinPath(g.prefix, path))
+}
+
 // Find 仅为满足接口，正常查找应走顶层 Router ；这里做前缀拼接后转发
 func (g *group) Find(method, path string) (ziface.Handler, map[string]string, []ziface.Middleware, bool) {
     return g.parent.Find(method, joinPath(g.prefix, path))
@@ -0,0 +1,249 @@
+package zrouter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SparkleBo/zinx/ziface"
+)
+
+// named 返回以名称作为错误的处理器，便于断言命中了哪条路由
+func named(name string) ziface.Handler {
+    return func(ziface.Context) error { return errors.New(name) }
+}
+
+type findCase struct {
+    method, path string
+    want         string // 期望命中的路由名称，空表示不应命中
+    params       map[string]string
+}
+
+func checkFind(t *testing.T, r ziface.Router, cases []findCase) {
+    t.Helper()
+    for _, c := range cases {
+        h, params, _, ok := r.Find(c.method, c.path)
+        if c.want == "" {
+            if ok { t.Errorf("%s %s: expected no match, got %v", c.method, c.path, h(nil)) }
+            continue
+        }
+        if !ok { t.Errorf("%s %s: expected %s, got no match", c.method, c.path, c.want); continue }
+        if got := h(nil).Error(); got != c.want { t.Errorf("%s %s: expected %s, got %s", c.method, c.path, c.want, got) }
+        if c.params == nil { c.params = map[string]string{} }
+        if !reflect.DeepEqual(params, c.params) { t.Errorf("%s %s: expected params %v, got %v", c.method, c.path, c.params, params) }
+    }
+}
+
+func TestFind_BacktrackStaticToParam(t *testing.T) {
+    r := New()
+    r.Handle("GET", "/users/new", named("new"))
+    r.Handle("GET", "/users/:id/edit", named("edit"))
+    r.Handle("GET", "/users/:id", named("show"))
+    checkFind(t, r, []findCase{
+        {"GET", "/users/new", "new", nil},
+        // 静态分支 new 之下没有 edit，回溯到参数分支
+        {"GET", "/users/new/edit", "edit", map[string]string{"id": "new"}},
+        {"GET", "/users/42/edit", "edit", map[string]string{"id": "42"}},
+        {"GET", "/users/42", "show", map[string]string{"id": "42"}},
+        {"GET", "/users/new/delete", "", nil},
+        {"POST", "/users/new", "", nil},
+    })
+}
+
+func TestFind_Priority(t *testing.T) {
+    r := New()
+    r.Handle("GET", "/files/static", named("static"))
+    r.Handle("GET", "/files/:name", named("param"))
+    r.Handle("GET", "/files/*", named("wildcard"))
+    checkFind(t, r, []findCase{
+        {"GET", "/files/static", "static", nil},
+        {"GET", "/files/readme", "param", map[string]string{"name": "readme"}},
+        {"GET", "/files/static/deep", "wildcard", nil},
+        {"GET", "/files/a/b/c", "wildcard", nil},
+        // 剩余路径为空时 wildcard 同样匹配
+        {"GET", "/files", "wildcard", nil},
+    })
+}
+
+func TestFind_BacktrackDeep(t *testing.T) {
+    r := New()
+    r.Handle("GET", "/a/b/c/d", named("static"))
+    r.Handle("GET", "/a/:x/c/e", named("param"))
+    r.Handle("GET", "/a/b/*", named("wildcard"))
+    r.Handle("GET", "/p/:a/x/one", named("one"))
+    r.Handle("GET", "/p/:a/:b/two", named("two"))
+    checkFind(t, r, []findCase{
+        {"GET", "/a/b/c/d", "static", nil},
+        // 静态链走到 c 之后失败，先回退到同层 wildcard，再考虑更上层的参数分支
+        {"GET", "/a/b/c/e", "wildcard", nil},
+        {"GET", "/a/z/c/e", "param", map[string]string{"x": "z"}},
+        {"GET", "/a/z/c/d", "", nil},
+        // 失败分支中的参数不会残留
+        {"GET", "/p/1/x/two", "two", map[string]string{"a": "1", "b": "x"}},
+        {"GET", "/p/1/x/one", "one", map[string]string{"a": "1"}},
+    })
+}
+
+func TestFind_BacktrackToWildcard(t *testing.T) {
+    r := New()
+    r.Handle("GET", "/static/css/site.css", named("css"))
+    r.Handle("GET", "/static/*", named("assets"))
+    r.Handle("GET", "/:page", named("page"))
+    checkFind(t, r, []findCase{
+        {"GET", "/static/css/site.css", "css", nil},
+        {"GET", "/static/css/other.css", "assets", nil},
+        {"GET", "/static", "assets", nil},
+        {"GET", "/about", "page", map[string]string{"page": "about"}},
+        {"GET", "/about/team", "", nil},
+    })
+}
+
+func TestFind_Group(t *testing.T) {
+    r := New()
+    api := r.Group("/api")
+    api.Handle("GET", "/users/new", named("new"))
+    api.Handle("GET", "/users/:id/edit", named("edit"))
+    checkFind(t, r, []findCase{
+        {"GET", "/api/users/new/edit", "edit", map[string]string{"id": "new"}},
+        {"GET", "/users/new", "", nil},
+    })
+    checkFind(t, api, []findCase{{"GET", "/users/new", "new", nil}})
+}
+
+func TestAllowed(t *testing.T) {
+    r := New()
+    r.Handle("GET", "/users/:id", named("show"))
+    r.Handle("delete", "/users/:id", named("delete"))
+    r.Handle("POST", "/users", named("create"))
+    r.Handle("GET", "/assets/*", named("assets"))
+    api := r.Group("/api")
+    api.Handle("PUT", "/items/:id", named("put"))
+    for path, want := range map[string][]string{
+        "/users/7":       {"DELETE", "GET"},
+        "/users":         {"POST"},
+        "/assets/a/b":    {"GET"},
+        "/api/items/1":   {"PUT"},
+        "/missing":       nil,
+        "/users/7/extra": nil,
+    } {
+        if got := r.Allowed(path); !reflect.DeepEqual(got, want) { t.Errorf("Allowed(%s): expected %v, got %v", path, want, got) }
+    }
+    if got := api.Allowed("/items/1"); !reflect.DeepEqual(got, []string{"PUT"}) { t.Errorf("group Allowed: %v", got) }
+}
+
+// mustPanic 断言注册 panic，且信息包含全部 want 片段
+func mustPanic(t *testing.T, register func(), want ...string) {
+    t.Helper()
+    defer func() {
+        t.Helper()
+        v := recover()
+        if v == nil { t.Fatalf("expected panic containing %q", want); return }
+        msg := fmt.Sprint(v)
+        for _, w := range want {
+            if !strings.Contains(msg, w) { t.Fatalf("panic %q should contain %q", msg, w) }
+        }
+    }()
+    register()
+}
+
+func TestHandle_Conflicts(t *testing.T) {
+    r := New()
+    r.Handle("GET", "/users/:id/edit", named("edit"))
+    r.Handle("get", "/users/new", named("new"))
+
+    mustPanic(t, func() { r.Handle("GET", "/users/new", named("again")) }, "GET /users/new conflicts with GET /users/new", "duplicate")
+    mustPanic(t, func() { r.Group("/users").Handle("GET", "/:id/edit", named("again")) }, "GET /users/:id/edit conflicts with GET /users/:id/edit")
+    mustPanic(t, func() { r.Handle("GET", "/users/:name", named("name")) }, "GET /users/:name", "GET /users/:id/edit", ":name", ":id")
+    mustPanic(t, func() { r.Handle("GET", "/static/*/x", named("wc")) }, "GET /static/*/x", "wildcard must be the last segment")
+    mustPanic(t, func() { r.Handle("GET", "/a/:id/b/:id", named("dup")) }, "duplicate parameter :id")
+    mustPanic(t, func() { r.Handle("GET", "/a/:/b", named("empty")) }, "empty parameter name")
+
+    // 冲突的注册不会改动路由树
+    checkFind(t, r, []findCase{
+        {"GET", "/users/new", "new", nil},
+        {"GET", "/users/7/edit", "edit", map[string]string{"id": "7"}},
+        {"GET", "/users/7", "", nil},
+        {"GET", "/static/a/x", "", nil},
+    })
+
+    // 方法不同、参数名一致或仅前缀重叠的路由不算冲突
+    r.Handle("POST", "/users/new", named("post"))
+    r.Handle("GET", "/users/:id", named("show"))
+    r.Handle("POST", "/users/:name", named("rename"))
+    r.Handle("GET", "/users/new/*", named("rest"))
+    checkFind(t, r, []findCase{
+        {"GET", "/users/7", "show", map[string]string{"id": "7"}},
+        {"POST", "/users/bob", "rename", map[string]string{"name": "bob"}},
+        {"GET", "/users/new/a/b", "rest", nil},
+    })
+}
+
+func TestFind_NamedWildcard(t *testing.T) {
+    r := New()
+    r.Handle("GET", "/static/*filepath", named("static"))
+    r.Handle("GET", "/static/favicon.ico", named("favicon"))
+    r.Handle("ANY", "/proxy/:svc/*rest", named("proxy"))
+    r.Handle("GET", "/raw/*", named("raw"))
+    checkFind(t, r, []findCase{
+        {"GET", "/static/css/app.css", "static", map[string]string{"filepath": "css/app.css"}},
+        {"GET", "/static/favicon.ico", "favicon", nil},
+        // 余下路径为空：带或不带末尾的 /
+        {"GET", "/static/", "static", map[string]string{"filepath": ""}},
+        {"GET", "/static", "static", map[string]string{"filepath": ""}},
+        // 余下路径原样保留，包括末尾的 / 与连续的 /
+        {"GET", "/static/img/", "static", map[string]string{"filepath": "img/"}},
+        {"GET", "/static/a//b", "static", map[string]string{"filepath": "a//b"}},
+        {"ANY", "/proxy/billing/v1/invoices/", "proxy", map[string]string{"svc": "billing", "rest": "v1/invoices/"}},
+        {"ANY", "/proxy/billing", "proxy", map[string]string{"svc": "billing", "rest": ""}},
+        // 匿名 wildcard 不写入参数
+        {"GET", "/raw/x/y", "raw", nil},
+    })
+
+    mustPanic(t, func() { r.Handle("POST", "/static/*path", named("x")); r.Handle("POST", "/static/*file", named("y")) }, "POST /static/*file conflicts with POST /static/*path", "*file and *path")
+    mustPanic(t, func() { r.Handle("GET", "/raw/*name", named("x")) }, "GET /raw/*name conflicts with GET /raw/*")
+    mustPanic(t, func() { r.Handle("GET", "/files/:name/*name", named("x")) }, "duplicate parameter *name")
+}
+
+func TestFind_Constraints(t *testing.T) {
+    r := New()
+    r.Handle("GET", "/users/:id<int>", named("byID"))
+    r.Handle("GET", "/users/:uid<uuid>", named("byUUID"))
+    r.Handle("GET", "/users/:name", named("byName"))
+    r.Handle("GET", "/files/:name<[a-z0-9-]+>", named("file"))
+    r.Handle("GET", "/orders/:id<int>/items", named("items"))
+    r.Handle("GET", "/orders/:ref/*rest", named("fallback"))
+    checkFind(t, r, []findCase{
+        {"GET", "/users/42", "byID", map[string]string{"id": "42"}},
+        {"GET", "/users/-7", "byID", map[string]string{"id": "-7"}},
+        {"GET", "/users/6F9619FF-8B86-D011-B42D-00C04FC964FF", "byUUID", map[string]string{"uid": "6F9619FF-8B86-D011-B42D-00C04FC964FF"}},
+        // 约束不满足时落到无约束的参数
+        {"GET", "/users/bob", "byName", map[string]string{"name": "bob"}},
+        {"GET", "/users/99999999999999999999", "byName", map[string]string{"name": "99999999999999999999"}},
+        {"GET", "/files/report-2024", "file", map[string]string{"name": "report-2024"}},
+        // 正则整段匹配，部分匹配不算
+        {"GET", "/files/Report", "", nil},
+        {"GET", "/files/a_b", "", nil},
+        {"GET", "/orders/7/items", "items", map[string]string{"id": "7"}},
+        // 约束满足但后续段走不通时同样回溯
+        {"GET", "/orders/7/notes", "fallback", map[string]string{"ref": "7", "rest": "notes"}},
+        {"GET", "/orders/abc/items", "fallback", map[string]string{"ref": "abc", "rest": "items"}},
+    })
+    if got := r.Allowed("/files/Report"); got != nil { t.Fatalf("constraint failure should not count as allowed: %v", got) }
+
+    mustPanic(t, func() { r.Handle("GET", "/users/:n<int>", named("x")) }, "GET /users/:n<int> conflicts with GET /users/:id<int>", ":n<int> and :id<int>")
+    mustPanic(t, func() { r.Handle("GET", "/users/:id<int>", named("x")) }, "duplicate route")
+    mustPanic(t, func() { r.Handle("GET", "/bad/:id<[a-z>", named("x")) }, "GET /bad/:id<[a-z>", "invalid constraint")
+    mustPanic(t, func() { r.Handle("GET", "/bad/:id<int", named("x")) }, "malformed constraint")
+    mustPanic(t, func() { r.Handle("GET", "/bad/:id<>", named("x")) }, "malformed constraint")
+}
+
+func TestParseUUID(t *testing.T) {
+    u, err := ziface.ParseUUID("6F9619FF-8B86-D011-B42D-00C04FC964FF")
+    if err != nil { t.Fatal(err) }
+    if u.String() != "6f9619ff-8b86-d011-b42d-00c04fc964ff" { t.Fatalf("round trip: %s", u) }
+    for _, bad := range []string{"", "6f9619ff8b86d011b42d00c04fc964ff", "6f9619ff-8b86-d011-b42d-00c04fc964fg", "6f9619ff-8b86-d011-b42d_00c04fc964ff"} {
+        if _, err := ziface.ParseUUID(bad); err == nil { t.Fatalf("expected error for %q", bad) }
+    }
+}
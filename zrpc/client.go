@@ -0,0 +1,225 @@
+package zrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/SparkleBo/zinx/zcodec"
+)
+
+// ServerError 服务端返回的调用错误
+type ServerError string
+
+func (e ServerError) Error() string { return string(e) }
+
+// call 一次在途调用，done 在收到响应或连接断开时写入
+type call struct {
+	msgID uint32
+	data  []byte
+	err   error
+	done  chan struct{}
+}
+
+// Client RPC 客户端，在一条连接上多路复用并发调用，可被多个 goroutine 同时使用
+type Client struct {
+	conn    net.Conn
+	pack    *Pack
+	codec   zcodec.Codec
+	timeout time.Duration
+	maxSize uint32
+
+	wmu sync.Mutex // 串行化写
+
+	mu      sync.Mutex
+	seq     uint32
+	pending map[uint32]*call
+	err     error // 连接断开的原因，非 nil 后不再接受调用
+	closed  chan struct{}
+}
+
+// ClientOption 客户端可选项
+type ClientOption func(c *Client)
+
+// WithClientCodec 设置参数与返回值的编解码器，须与服务端一致，默认 zcodec.JSON
+func WithClientCodec(codec zcodec.Codec) ClientOption {
+	return func(c *Client) { c.codec = codec }
+}
+
+// WithCallTimeout 设置调用的默认超时，仅在调用的 ctx 没有截止时间时生效，0 表示不限
+func WithCallTimeout(d time.Duration) ClientOption {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithClientMaxPacketSize 限制单个响应数据的最大字节数，默认 4096，0 表示不限制
+func WithClientMaxPacketSize(n uint32) ClientOption {
+	return func(c *Client) { c.maxSize = n }
+}
+
+// Dial 连接 RPC 服务端
+func Dial(network, addr string, opts ...ClientOption) (*Client, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, opts...), nil
+}
+
+// NewClient 在已建立的连接上创建客户端，并启动读协程
+func NewClient(conn net.Conn, opts ...ClientOption) *Client {
+	c := &Client{
+		conn:    conn,
+		pack:    NewPack(),
+		codec:   zcodec.JSON{},
+		maxSize: 4096,
+		pending: make(map[uint32]*call),
+		closed:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	go c.readLoop()
+	return c
+}
+
+// Call 调用 method 并将结果解码到 reply。ctx 的截止时间（或默认超时）同时传给服务端，
+// 服务端方法的 ctx 在同一时刻到期；超时或取消时返回 ctx.Err()，迟到的响应被丢弃
+func (c *Client) Call(ctx context.Context, method string, args, reply any) error {
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	var timeoutMs uint64
+	if deadline, ok := ctx.Deadline(); ok {
+		remain := time.Until(deadline)
+		if remain <= 0 {
+			return context.DeadlineExceeded
+		}
+		timeoutMs = uint64((remain + time.Millisecond - 1) / time.Millisecond)
+	}
+	body, err := c.codec.Encode(args)
+	if err != nil {
+		return fmt.Errorf("zrpc: encode args: %w", err)
+	}
+
+	cl := &call{done: make(chan struct{})}
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.seq++
+	if c.seq == 0 {
+		c.seq = 1
+	}
+	reqID := c.seq
+	c.pending[reqID] = cl
+	c.mu.Unlock()
+
+	buf, _ := c.pack.Pack(NewFrame(MsgIDCall, reqID, appendCall(nil, method, timeoutMs, body)))
+	c.wmu.Lock()
+	// 每次都重新设置：上一次调用留下的截止时间已过期，不清除会让无截止时间的调用写超时
+	deadline, _ := ctx.Deadline()
+	_ = c.conn.SetWriteDeadline(deadline)
+	_, err = c.conn.Write(buf)
+	c.wmu.Unlock()
+	if err != nil {
+		c.remove(reqID)
+		c.shutdown(err)
+		return err
+	}
+
+	select {
+	case <-cl.done:
+	case <-ctx.Done():
+		c.remove(reqID)
+		return ctx.Err()
+	}
+	if cl.err != nil {
+		return cl.err
+	}
+	if cl.msgID == MsgIDError {
+		return ServerError(cl.data)
+	}
+	if err := c.codec.Decode(cl.data, reply); err != nil {
+		return fmt.Errorf("zrpc: decode reply: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) remove(reqID uint32) {
+	c.mu.Lock()
+	delete(c.pending, reqID)
+	c.mu.Unlock()
+}
+
+// readLoop 读取响应并按请求 ID 交给对应的调用
+func (c *Client) readLoop() {
+	head := make([]byte, headLen)
+	for {
+		if _, err := io.ReadFull(c.conn, head); err != nil {
+			c.shutdown(err)
+			return
+		}
+		msg, _ := c.pack.Unpack(head)
+		f := msg.(*Frame)
+		if c.maxSize > 0 && f.DataLen > c.maxSize {
+			c.shutdown(fmt.Errorf("zrpc: reply of %d bytes exceeds limit %d", f.DataLen, c.maxSize))
+			return
+		}
+		data := make([]byte, f.DataLen)
+		if _, err := io.ReadFull(c.conn, data); err != nil {
+			c.shutdown(err)
+			return
+		}
+		if f.ID != MsgIDReply && f.ID != MsgIDError {
+			continue
+		}
+		c.mu.Lock()
+		cl := c.pending[f.ReqID]
+		delete(c.pending, f.ReqID)
+		c.mu.Unlock()
+		if cl == nil {
+			continue // 已超时放弃的调用
+		}
+		cl.msgID, cl.data = f.ID, data
+		close(cl.done)
+	}
+}
+
+// shutdown 连接断开，所有在途调用以 ErrShutdown 失败
+func (c *Client) shutdown(cause error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.err = ErrShutdown
+	if cause != nil && !errors.Is(cause, net.ErrClosed) {
+		c.err = fmt.Errorf("%w: %v", ErrShutdown, cause)
+	}
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+	_ = c.conn.Close()
+	for _, cl := range pending {
+		cl.err = c.err
+		close(cl.done)
+	}
+	close(c.closed)
+}
+
+// Close 关闭连接，在途调用返回 ErrShutdown
+func (c *Client) Close() error {
+	c.shutdown(nil)
+	<-c.closed
+	return nil
+}
+
+// Done 连接断开时关闭
+func (c *Client) Done() <-chan struct{} { return c.closed }
@@ -0,0 +1,99 @@
+// Package zrpc 基于 znet 的轻量 RPC：服务端注册类型化方法，客户端在一条连接上并发多路复用调用。
+//
+// 帧格式在 znet TLV 包头后追加请求 ID，响应按请求 ID 与调用对应：
+//
+//	| MsgID 4B | DataLen 4B | ReqID 4B | Data DataLen B |
+//
+// 调用帧（MsgIDCall）的数据为 方法名长度(uvarint) | 方法名 | 超时毫秒数(uvarint，0 表示不限) | 参数，
+// 参数与返回值按服务器配置的 zcodec 编解码器编码
+package zrpc
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/znet"
+)
+
+const (
+	// MsgIDCall 客户端发起的调用
+	MsgIDCall uint32 = 1
+	// MsgIDReply 调用成功，数据为编码后的返回值
+	MsgIDReply uint32 = 2
+	// MsgIDError 调用失败，数据为错误信息
+	MsgIDError uint32 = 3
+)
+
+const headLen = 12
+
+var errShortHead = errors.New("zrpc: packet head too short")
+
+// Frame 携带请求 ID 的消息
+type Frame struct {
+	znet.Message
+	ReqID uint32
+}
+
+// NewFrame 以 MsgID、请求 ID 与数据构造帧
+func NewFrame(msgID, reqID uint32, data []byte) *Frame {
+	return &Frame{Message: *znet.NewMessage(msgID, data), ReqID: reqID}
+}
+
+// Pack RPC 帧的封包/拆包实现，非 Frame 消息（如心跳）的请求 ID 为 0
+type Pack struct{}
+
+func NewPack() *Pack { return &Pack{} }
+
+func (p *Pack) GetHeadLen() uint32 { return headLen }
+
+func (p *Pack) Pack(msg ziface.IMessage) ([]byte, error) {
+	var reqID uint32
+	if f, ok := msg.(*Frame); ok {
+		reqID = f.ReqID
+	}
+	data := msg.GetData()
+	buf := make([]byte, headLen+len(data))
+	binary.LittleEndian.PutUint32(buf[0:4], msg.GetMsgID())
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(data)))
+	binary.LittleEndian.PutUint32(buf[8:12], reqID)
+	copy(buf[headLen:], data)
+	return buf, nil
+}
+
+func (p *Pack) Unpack(head []byte) (ziface.IMessage, error) {
+	if len(head) < headLen {
+		return nil, errShortHead
+	}
+	f := &Frame{ReqID: binary.LittleEndian.Uint32(head[8:12])}
+	f.ID = binary.LittleEndian.Uint32(head[0:4])
+	f.DataLen = binary.LittleEndian.Uint32(head[4:8])
+	return f, nil
+}
+
+// appendCall 编码调用帧数据
+func appendCall(b []byte, method string, timeoutMs uint64, args []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(method)))
+	b = append(b, method...)
+	b = binary.AppendUvarint(b, timeoutMs)
+	return append(b, args...)
+}
+
+var errBadCall = errors.New("zrpc: malformed call frame")
+
+// parseCall 解码调用帧数据，args 引用 data
+func parseCall(data []byte) (method string, timeoutMs uint64, args []byte, err error) {
+	n, k := binary.Uvarint(data)
+	if k <= 0 || n > uint64(len(data)-k) {
+		return "", 0, nil, errBadCall
+	}
+	data = data[k:]
+	method, data = string(data[:n]), data[n:]
+	timeoutMs, k = binary.Uvarint(data)
+	if k <= 0 {
+		return "", 0, nil, errBadCall
+	}
+	return method, timeoutMs, data[k:], nil
+}
+
+var _ ziface.IDataPack = (*Pack)(nil)
@@ -0,0 +1,201 @@
+package zrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SparkleBo/zinx/zcodec"
+	"github.com/SparkleBo/zinx/znet"
+)
+
+type ArithArgs struct {
+	A     int64 `pb:"1" json:"a"`
+	B     int64 `pb:"2" json:"b"`
+	Sleep int64 `pb:"3" json:"sleep"` // 毫秒
+}
+
+type ArithReply struct {
+	Sum int64 `pb:"1" json:"sum"`
+}
+
+type Arith struct {
+	cancelled atomic.Int32
+}
+
+func (a *Arith) Add(ctx context.Context, args *ArithArgs) (*ArithReply, error) {
+	if args.Sleep > 0 {
+		select {
+		case <-time.After(time.Duration(args.Sleep) * time.Millisecond):
+		case <-ctx.Done():
+			a.cancelled.Add(1)
+			return nil, ctx.Err()
+		}
+	}
+	return &ArithReply{Sum: args.A + args.B}, nil
+}
+
+func (a *Arith) Div(ctx context.Context, args *ArithArgs) (*ArithReply, error) {
+	if args.B == 0 { return nil, errors.New("divide by zero") }
+	return &ArithReply{Sum: args.A / args.B}, nil
+}
+
+func (a *Arith) Panic(ctx context.Context, args *ArithArgs) (*ArithReply, error) { panic("boom") }
+
+// 签名不符的方法被忽略
+func (a *Arith) Helper() {}
+
+func newTestServer(t *testing.T, opts ...znet.Option) (*Server, *Arith) {
+	t.Helper()
+	s := NewServer("rpc", opts...)
+	s.Port = 0
+	arith := &Arith{}
+	if err := s.Register(arith); err != nil { t.Fatal(err) }
+	s.Start()
+	t.Cleanup(func() { _ = s.Stop(context.Background()) })
+	return s, arith
+}
+
+func TestRPC_ConcurrentCalls(t *testing.T) {
+	for _, codec := range []zcodec.Codec{zcodec.JSON{}, zcodec.Proto{}} {
+		s, _ := newTestServer(t, znet.WithCodec(codec))
+		c, err := Dial("tcp", s.Addr().String(), WithClientCodec(codec))
+		if err != nil { t.Fatal(err) }
+
+		// 耗时递减的并发调用在同一连接上乱序完成，仍需各自拿到正确结果
+		var wg sync.WaitGroup
+		errs := make(chan error, 50)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var reply ArithReply
+				if err := c.Call(context.Background(), "Arith.Add", &ArithArgs{A: int64(i), B: 1000, Sleep: int64(50 - i)}, &reply); err != nil {
+					errs <- err
+					return
+				}
+				if reply.Sum != int64(i)+1000 { errs <- fmt.Errorf("call %d got %d", i, reply.Sum) }
+			}(i)
+		}
+		start := time.Now()
+		wg.Wait()
+		close(errs)
+		for err := range errs { t.Fatalf("%s: %v", codec.Name(), err) }
+		// 串行执行至少需要 sum(1..50) ms
+		if d := time.Since(start); d > time.Second { t.Fatalf("%s: calls not concurrent, took %v", codec.Name(), d) }
+		c.Close()
+	}
+}
+
+func TestRPC_Errors(t *testing.T) {
+	s, _ := newTestServer(t)
+	c, err := Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+
+	var reply ArithReply
+	err = c.Call(context.Background(), "Arith.Div", &ArithArgs{A: 1}, &reply)
+	if se, ok := err.(ServerError); !ok || se != "divide by zero" { t.Fatalf("expected ServerError, got %v", err) }
+	if err := c.Call(context.Background(), "Arith.Nope", &ArithArgs{}, &reply); err == nil || err.Error() != "zrpc: unknown method Arith.Nope" { t.Fatalf("unexpected unknown method err %v", err) }
+	if err := c.Call(context.Background(), "Arith.Helper", &ArithArgs{}, &reply); err == nil { t.Fatalf("helper should not be registered") }
+	if err := c.Call(context.Background(), "Arith.Panic", &ArithArgs{}, &reply); err == nil { t.Fatalf("expected panic converted to error") }
+	// 错误之后连接仍可用
+	if err := c.Call(context.Background(), "Arith.Div", &ArithArgs{A: 9, B: 3}, &reply); err != nil || reply.Sum != 3 { t.Fatalf("call after errors: %v %d", err, reply.Sum) }
+
+	if err := s.Register(&Arith{}); err == nil { t.Fatalf("expected duplicate registration error") }
+	if err := Handle(s, "Math.Neg", func(ctx context.Context, a *ArithArgs) (*ArithReply, error) { return &ArithReply{Sum: -a.A}, nil }); err != nil { t.Fatal(err) }
+	if err := c.Call(context.Background(), "Math.Neg", &ArithArgs{A: 5}, &reply); err != nil || reply.Sum != -5 { t.Fatalf("Handle: %v %d", err, reply.Sum) }
+}
+
+func TestRPC_Timeout(t *testing.T) {
+	s, arith := newTestServer(t)
+	c, err := Dial("tcp", s.Addr().String(), WithCallTimeout(50*time.Millisecond))
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+
+	var reply ArithReply
+	if err := c.Call(context.Background(), "Arith.Add", &ArithArgs{Sleep: 2000}, &reply); !errors.Is(err, context.DeadlineExceeded) { t.Fatalf("expected deadline exceeded, got %v", err) }
+	// 超时同时传给服务端，方法的 ctx 随之到期
+	deadline := time.Now().Add(3 * time.Second)
+	for arith.cancelled.Load() == 0 {
+		if time.Now().After(deadline) { t.Fatalf("server ctx not cancelled") }
+		time.Sleep(5 * time.Millisecond)
+	}
+	// 调用方自带的截止时间优先于默认超时
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.Call(ctx, "Arith.Add", &ArithArgs{A: 1, B: 2, Sleep: 100}, &reply); err != nil || reply.Sum != 3 { t.Fatalf("ctx deadline should override default: %v", err) }
+}
+
+func TestRPC_DeadlineThenBackground(t *testing.T) {
+	s, _ := newTestServer(t)
+	c, err := Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+
+	var reply ArithReply
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Call(ctx, "Arith.Add", &ArithArgs{A: 1, B: 1}, &reply); err != nil { t.Fatal(err) }
+	// 上一次调用的写截止时间过期后，无截止时间的调用仍可正常写出
+	time.Sleep(50 * time.Millisecond)
+	if err := c.Call(context.Background(), "Arith.Add", &ArithArgs{A: 2, B: 3}, &reply); err != nil || reply.Sum != 5 { t.Fatalf("call without deadline: %v %d", err, reply.Sum) }
+}
+
+func TestRPC_Overloaded(t *testing.T) {
+	s := NewServer("rpc")
+	s.Port = 0
+	s.SetMaxConcurrency(2)
+	if err := s.Register(&Arith{}); err != nil { t.Fatal(err) }
+	s.Start()
+	defer s.Stop(context.Background())
+	c, err := Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var reply ArithReply
+			if err := c.Call(context.Background(), "Arith.Add", &ArithArgs{Sleep: 300}, &reply); err != nil { t.Errorf("slow call: %v", err) }
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	// 在途调用达到上限时新调用立即被拒绝，而不是再起 goroutine
+	var reply ArithReply
+	if err := c.Call(context.Background(), "Arith.Add", &ArithArgs{A: 1}, &reply); err == nil || err.Error() != ErrOverloaded.Error() { t.Fatalf("expected overload error, got %v", err) }
+	wg.Wait()
+	if err := c.Call(context.Background(), "Arith.Add", &ArithArgs{A: 1, B: 2}, &reply); err != nil || reply.Sum != 3 { t.Fatalf("call after overload: %v %d", err, reply.Sum) }
+}
+
+func TestRPC_GracefulStop(t *testing.T) {
+	s, _ := newTestServer(t)
+	c, err := Dial("tcp", s.Addr().String())
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		var reply ArithReply
+		err := c.Call(context.Background(), "Arith.Add", &ArithArgs{A: 1, B: 1, Sleep: 200}, &reply)
+		if err == nil && reply.Sum != 2 { err = fmt.Errorf("got %d", reply.Sum) }
+		result <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := s.Stop(context.Background()); err != nil { t.Fatal(err) }
+	// 停止前已开始的调用正常完成，之后连接断开
+	if err := <-result; err != nil { t.Fatalf("in-flight call failed: %v", err) }
+	select {
+	case <-c.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatalf("client not notified of shutdown")
+	}
+	var reply ArithReply
+	if err := c.Call(context.Background(), "Arith.Add", &ArithArgs{}, &reply); !errors.Is(err, ErrShutdown) { t.Fatalf("expected ErrShutdown, got %v", err) }
+}
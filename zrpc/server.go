@@ -0,0 +1,219 @@
+package zrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/SparkleBo/zinx/ziface"
+	"github.com/SparkleBo/zinx/znet"
+)
+
+var (
+	ErrShutdown      = errors.New("zrpc: shut down")
+	ErrUnknownMethod = errors.New("zrpc: unknown method")
+	ErrOverloaded    = errors.New("zrpc: server overloaded")
+)
+
+// defaultMaxConcurrency 默认的在途调用上限
+const defaultMaxConcurrency = 1024
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// method 已注册的方法：call 以解码后的参数指针调用，返回值为结果指针
+type method struct {
+	argType reflect.Type
+	call    func(ctx context.Context, arg any) (any, error)
+}
+
+// Server RPC 服务端，嵌入 znet.Server，可直接使用其钩子、心跳等能力。
+// 每个调用在独立 goroutine 中执行，同一连接上的调用可以并发、乱序完成；
+// 在途调用数达到上限时新调用直接以 ErrOverloaded 应答，见 SetMaxConcurrency
+type Server struct {
+	*znet.Server
+
+	mu       sync.RWMutex
+	methods  map[string]*method
+	draining bool
+	inflight sync.WaitGroup
+	// sem 在途调用的信号量，nil 表示不限制
+	sem chan struct{}
+}
+
+// NewServer 创建使用 RPC 帧格式的 znet 服务器，opts 中的 znet.WithPacket 会被忽略
+func NewServer(name string, opts ...znet.Option) *Server {
+	s := &Server{methods: make(map[string]*method), sem: make(chan struct{}, defaultMaxConcurrency)}
+	opts = append(opts, znet.WithPacket(NewPack()))
+	s.Server = znet.NewServer(name, opts...).(*znet.Server)
+	s.AddRouter(MsgIDCall, s.handleCall)
+	return s
+}
+
+// SetMaxConcurrency 设置全部连接合计的在途调用上限，默认 1024，<= 0 表示不限制；须在 Start 之前调用
+func (s *Server) SetMaxConcurrency(n int) {
+	s.sem = nil
+	if n > 0 {
+		s.sem = make(chan struct{}, n)
+	}
+}
+
+// Register 以 svc 的类型名作为服务名注册其方法，见 RegisterName
+func (s *Server) Register(svc any) error {
+	t := reflect.TypeOf(svc)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return s.RegisterName(t.Name(), svc)
+}
+
+// RegisterName 注册 svc 中形如 func(ctx context.Context, args *A) (*R, error) 的导出方法，
+// 调用名为 "服务名.方法名"，其它签名的方法被忽略
+func (s *Server) RegisterName(name string, svc any) error {
+	v := reflect.ValueOf(svc)
+	t := v.Type()
+	registered := 0
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		mt := m.Type
+		if mt.NumIn() != 3 || mt.NumOut() != 2 || mt.In(1) != contextType ||
+			mt.In(2).Kind() != reflect.Pointer || mt.Out(0).Kind() != reflect.Pointer || mt.Out(1) != errorType {
+			continue
+		}
+		fn := v.Method(i)
+		if err := s.add(name+"."+m.Name, &method{
+			argType: mt.In(2).Elem(),
+			call: func(ctx context.Context, arg any) (any, error) {
+				out := fn.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(arg)})
+				err, _ := out[1].Interface().(error)
+				return out[0].Interface(), err
+			},
+		}); err != nil {
+			return err
+		}
+		registered++
+	}
+	if registered == 0 {
+		return fmt.Errorf("zrpc: service %s has no suitable methods", name)
+	}
+	return nil
+}
+
+// Handle 以函数注册单个方法
+func Handle[A, R any](s *Server, name string, fn func(ctx context.Context, args *A) (*R, error)) error {
+	return s.add(name, &method{
+		argType: reflect.TypeOf((*A)(nil)).Elem(),
+		call:    func(ctx context.Context, arg any) (any, error) { return fn(ctx, arg.(*A)) },
+	})
+}
+
+func (s *Server) add(name string, m *method) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.methods[name]; dup {
+		return fmt.Errorf("zrpc: method %s already registered", name)
+	}
+	s.methods[name] = m
+	return nil
+}
+
+// handleCall 在 worker 中解析调用帧，方法本身在独立 goroutine 中执行
+func (s *Server) handleCall(req ziface.IRequest) error {
+	conn := req.GetConnection()
+	f, ok := req.GetMessage().(*Frame)
+	if !ok {
+		return errors.New("zrpc: request is not an rpc frame")
+	}
+	name, timeoutMs, args, err := parseCall(req.GetData())
+	if err != nil {
+		return replyError(conn, f.ReqID, err)
+	}
+	s.mu.RLock()
+	m := s.methods[name]
+	draining := s.draining
+	if m != nil && !draining {
+		s.inflight.Add(1)
+	}
+	s.mu.RUnlock()
+	switch {
+	case draining:
+		return replyError(conn, f.ReqID, ErrShutdown)
+	case m == nil:
+		return replyError(conn, f.ReqID, fmt.Errorf("%w %s", ErrUnknownMethod, name))
+	}
+	if s.sem != nil {
+		select {
+		case s.sem <- struct{}{}:
+		default:
+			s.inflight.Done()
+			return replyError(conn, f.ReqID, ErrOverloaded)
+		}
+	}
+	// 请求数据在处理函数返回后归还缓冲池，异步执行前先拷贝参数
+	args = append([]byte(nil), args...)
+	go func() {
+		defer s.inflight.Done()
+		if s.sem != nil {
+			defer func() { <-s.sem }()
+		}
+		ctx := conn.Context()
+		if timeoutMs > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutMs)*time.Millisecond)
+			defer cancel()
+		}
+		data, err := s.invoke(ctx, m, args)
+		if err != nil {
+			_ = replyError(conn, f.ReqID, err)
+			return
+		}
+		if err := conn.SendMsg(NewFrame(MsgIDReply, f.ReqID, data)); err != nil {
+			fmt.Printf("[WARN]zrpc reply %s to conn %d failed, err: %v\n", name, conn.GetConnID(), err)
+		}
+	}()
+	return nil
+}
+
+// invoke 解码参数、调用方法并编码结果，方法 panic 时转换为错误
+func (s *Server) invoke(ctx context.Context, m *method, args []byte) (data []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("zrpc: method panic: %v", r)
+		}
+	}()
+	arg := reflect.New(m.argType).Interface()
+	if err := s.Codec().Decode(args, arg); err != nil {
+		return nil, fmt.Errorf("zrpc: decode args: %w", err)
+	}
+	reply, err := m.call(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	return s.Codec().Encode(reply)
+}
+
+func replyError(conn ziface.IConnection, reqID uint32, err error) error {
+	return conn.SendMsg(NewFrame(MsgIDError, reqID, []byte(err.Error())))
+}
+
+// Stop 先拒绝新调用并等待在途调用返回，再优雅停止底层 znet 服务器；ctx 到期时不再等待
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return s.Server.Stop(ctx)
+}
@@ -0,0 +1,26 @@
+package ztimer
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultTick 默认时间轮精度
+const defaultTick = 10 * time.Millisecond
+
+var (
+	defaultOnce  sync.Once
+	defaultWheel *Wheel
+)
+
+// Default 返回进程级共享时间轮（精度 defaultTick），首次调用时启动
+func Default() *Wheel {
+	defaultOnce.Do(func() { defaultWheel = New(defaultTick) })
+	return defaultWheel
+}
+
+// AfterFunc 在共享时间轮上注册一次性任务
+func AfterFunc(d time.Duration, fn func()) *Timer { return Default().AfterFunc(d, fn) }
+
+// Every 在共享时间轮上注册周期任务
+func Every(d time.Duration, fn func()) *Timer { return Default().Every(d, fn) }
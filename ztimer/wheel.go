@@ -0,0 +1,268 @@
+// Package ztimer 分层时间轮：以固定 tick 推进，O(1) 插入与取消，适合管理海量连接超时、重试与心跳。
+//
+// 共 wheelLevels 层，每层 wheelSize 个槽位；第 0 层每槽跨 1 个 tick，第 i 层每槽跨 wheelSize^i 个 tick。
+// 定时任务按剩余 tick 数放入对应层级，高层槽位到期时整体降级（cascade）到低层，最终在第 0 层触发。
+package ztimer
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	wheelBits   = 6
+	wheelSize   = 1 << wheelBits
+	wheelMask   = wheelSize - 1
+	wheelLevels = 6
+	// maxTicks 可直接放入时间轮的最大剩余 tick 数，更远的任务先放在最高层，降级时重新计算位置
+	maxTicks = 1<<(wheelBits*wheelLevels) - 1
+)
+
+// Timer 时间轮中的定时任务，由 Wheel.AfterFunc 或 Wheel.Every 创建
+type Timer struct {
+	w      *Wheel
+	expire int64 // 到期 tick
+	period int64 // 周期 tick 数，0 表示一次性任务
+	fn     func()
+
+	// 槽位内的侵入式双向链表，slot 非 nil 表示等待触发
+	prev, next *Timer
+	slot       *slot
+}
+
+// slot 时间轮槽位，带哨兵节点的环形链表
+type slot struct {
+	head Timer
+}
+
+func (s *slot) init() {
+	s.head.prev = &s.head
+	s.head.next = &s.head
+}
+
+func (s *slot) push(t *Timer) {
+	t.prev = s.head.prev
+	t.next = &s.head
+	s.head.prev.next = t
+	s.head.prev = t
+	t.slot = s
+}
+
+func (s *slot) remove(t *Timer) {
+	t.prev.next = t.next
+	t.next.prev = t.prev
+	t.prev, t.next, t.slot = nil, nil, nil
+}
+
+// take 取出槽位内全部任务并清空槽位
+func (s *slot) take() []*Timer {
+	var ts []*Timer
+	for t := s.head.next; t != &s.head; {
+		next := t.next
+		t.prev, t.next, t.slot = nil, nil, nil
+		ts = append(ts, t)
+		t = next
+	}
+	s.init()
+	return ts
+}
+
+// Wheel 分层时间轮。任务回调在时间轮的驱动 goroutine 中串行执行，应尽快返回，耗时逻辑请自行另起 goroutine
+type Wheel struct {
+	tick time.Duration
+
+	mu     sync.Mutex
+	levels [wheelLevels][wheelSize]slot
+	now    int64 // 下一个待处理的 tick
+	count  int
+
+	quit chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// New 创建以 tick 为精度的时间轮并启动驱动 goroutine，tick <= 0 时取 defaultTick
+func New(tick time.Duration) *Wheel {
+	w := newWheel(tick)
+	go w.run()
+	return w
+}
+
+func newWheel(tick time.Duration) *Wheel {
+	if tick <= 0 {
+		tick = defaultTick
+	}
+	w := &Wheel{tick: tick, quit: make(chan struct{}), done: make(chan struct{})}
+	for i := range w.levels {
+		for j := range w.levels[i] {
+			w.levels[i][j].init()
+		}
+	}
+	return w
+}
+
+// Tick 返回时间轮精度
+func (w *Wheel) Tick() time.Duration { return w.tick }
+
+// Len 返回等待触发的任务数
+func (w *Wheel) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// AfterFunc 在至少 d 之后调用一次 fn，实际触发时间向上取整到 tick
+func (w *Wheel) AfterFunc(d time.Duration, fn func()) *Timer {
+	t := &Timer{w: w, fn: fn}
+	w.mu.Lock()
+	t.expire = w.now + w.ticks(d)
+	w.add(t)
+	w.mu.Unlock()
+	return t
+}
+
+// Every 每隔 d 调用一次 fn，直到 Timer.Stop；周期按到期 tick 累加，不随回调耗时漂移
+func (w *Wheel) Every(d time.Duration, fn func()) *Timer {
+	t := &Timer{w: w, fn: fn}
+	w.mu.Lock()
+	t.period = w.ticks(d)
+	t.expire = w.now + t.period
+	w.add(t)
+	w.mu.Unlock()
+	return t
+}
+
+// Stop 停止驱动 goroutine，尚未触发的任务不再执行
+func (w *Wheel) Stop() {
+	w.once.Do(func() {
+		close(w.quit)
+		<-w.done
+	})
+}
+
+// ticks 将时长换算为 tick 数，向上取整且至少为 1
+func (w *Wheel) ticks(d time.Duration) int64 {
+	n := int64((d + w.tick - 1) / w.tick)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// add 按剩余 tick 数将任务放入对应层级的槽位，调用方持有 w.mu
+func (w *Wheel) add(t *Timer) {
+	if t.expire < w.now {
+		t.expire = w.now
+	}
+	expire := t.expire
+	if expire-w.now > maxTicks {
+		expire = w.now + maxTicks
+	}
+	delta := expire - w.now
+	level := 0
+	for level < wheelLevels-1 && delta >= 1<<(wheelBits*(level+1)) {
+		level++
+	}
+	idx := (expire >> (wheelBits * level)) & wheelMask
+	w.levels[level][idx].push(t)
+	w.count++
+}
+
+// run 按真实时间推进时间轮，驱动 goroutine 落后时连续补处理
+func (w *Wheel) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+	start := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			w.advance(int64(now.Sub(start) / w.tick))
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// advance 处理 tick 直到 target（含）
+func (w *Wheel) advance(target int64) {
+	for {
+		w.mu.Lock()
+		if w.now > target {
+			w.mu.Unlock()
+			return
+		}
+		fns := w.step()
+		w.mu.Unlock()
+		for _, fn := range fns {
+			fn()
+		}
+	}
+}
+
+// step 处理当前 tick：必要时逐层降级，再取出第 0 层到期任务；周期任务在回调执行前重新入轮，
+// 以便回调内调用 Stop 能够取消。调用方持有 w.mu
+func (w *Wheel) step() []func() {
+	if w.now&wheelMask == 0 {
+		for level := 1; level < wheelLevels; level++ {
+			idx := (w.now >> (wheelBits * level)) & wheelMask
+			for _, t := range w.levels[level][idx].take() {
+				w.count--
+				w.add(t)
+			}
+			if idx != 0 {
+				break
+			}
+		}
+	}
+	var fns []func()
+	for _, t := range w.levels[0][w.now&wheelMask].take() {
+		w.count--
+		if t.expire > w.now {
+			w.add(t)
+			continue
+		}
+		if t.period > 0 {
+			t.expire += t.period
+			if t.expire <= w.now {
+				t.expire = w.now + 1
+			}
+			w.add(t)
+		}
+		fns = append(fns, t.fn)
+	}
+	w.now++
+	return fns
+}
+
+// Stop 取消任务，返回任务是否仍在等待触发；周期任务可在自身回调中调用
+func (t *Timer) Stop() bool {
+	w := t.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if t.slot == nil {
+		return false
+	}
+	t.slot.remove(t)
+	w.count--
+	return true
+}
+
+// Reset 取消尚未触发的任务并在 d 之后重新触发，周期任务同时将周期改为 d；返回任务重置前是否在等待触发
+func (t *Timer) Reset(d time.Duration) bool {
+	w := t.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pending := t.slot != nil
+	if pending {
+		t.slot.remove(t)
+		w.count--
+	}
+	n := w.ticks(d)
+	if t.period > 0 {
+		t.period = n
+	}
+	t.expire = w.now + n
+	w.add(t)
+	return pending
+}
@@ -0,0 +1,99 @@
+package ztimer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试直接驱动 tick，不依赖真实时间
+func TestWheel_AfterFunc(t *testing.T) {
+	w := newWheel(time.Millisecond)
+	var fired []int64
+	for _, d := range []int64{1, 5, 63, 64, 65, 4095, 4096, 300000} {
+		d := d
+		w.AfterFunc(time.Duration(d)*time.Millisecond, func() { fired = append(fired, d) })
+	}
+	if w.Len() != 8 { t.Fatalf("expected 8 pending, got %d", w.Len()) }
+	for tick := int64(0); tick <= 300000; tick++ {
+		before := len(fired)
+		w.advance(tick)
+		if len(fired) > before && fired[len(fired)-1] != tick {
+			t.Fatalf("timer %d fired at tick %d", fired[len(fired)-1], tick)
+		}
+	}
+	if len(fired) != 8 { t.Fatalf("expected 8 fired, got %v", fired) }
+	if w.Len() != 0 { t.Fatalf("expected empty wheel, got %d", w.Len()) }
+}
+
+func TestWheel_BeyondMaxTicks(t *testing.T) {
+	w := newWheel(time.Millisecond)
+	var at int64 = -1
+	expire := int64(maxTicks) + 10
+	tm := w.AfterFunc(time.Duration(expire)*time.Millisecond, func() { at = w.now - 1 })
+	// 其间槽位均为空，直接跳到最高层槽位降级的 tick：第一次降级仍超出范围，重新放回最高层
+	w.now = 63 << (wheelBits * 5)
+	w.advance(w.now)
+	if at != -1 || w.Len() != 1 { t.Fatalf("timer should still be pending after first cascade") }
+	w.now = 1 << (wheelBits * 6)
+	w.advance(expire)
+	if at != expire { t.Fatalf("expected fire at %d, got %d", expire, at) }
+	if tm.Stop() { t.Fatalf("fired timer should not be pending") }
+}
+
+func TestWheel_StopAndReset(t *testing.T) {
+	w := newWheel(time.Millisecond)
+	var n atomic.Int32
+	tm := w.AfterFunc(10*time.Millisecond, func() { n.Add(1) })
+	if !tm.Stop() { t.Fatalf("expected pending timer") }
+	if tm.Stop() { t.Fatalf("second Stop should report not pending") }
+	w.advance(20)
+	if n.Load() != 0 { t.Fatalf("stopped timer fired") }
+
+	// Reset 后重新计时
+	if tm.Reset(10 * time.Millisecond) { t.Fatalf("stopped timer should not be pending") }
+	w.advance(30)
+	if n.Load() != 0 { t.Fatalf("reset timer fired early") }
+	w.advance(31)
+	if n.Load() != 1 { t.Fatalf("reset timer did not fire") }
+}
+
+func TestWheel_Every(t *testing.T) {
+	w := newWheel(time.Millisecond)
+	var ticks []int64
+	var tm *Timer
+	tm = w.Every(100*time.Millisecond, func() {
+		ticks = append(ticks, w.now-1)
+		if len(ticks) == 3 { tm.Stop() }
+	})
+	w.advance(1000)
+	if len(ticks) != 3 || ticks[0] != 100 || ticks[1] != 200 || ticks[2] != 300 { t.Fatalf("unexpected periodic ticks %v", ticks) }
+	if w.Len() != 0 { t.Fatalf("stopped periodic timer still pending") }
+}
+
+func TestWheel_RealTime(t *testing.T) {
+	w := New(time.Millisecond)
+	defer w.Stop()
+	start := time.Now()
+	fired := make(chan time.Duration, 1)
+	w.AfterFunc(30*time.Millisecond, func() { fired <- time.Since(start) })
+	select {
+	case d := <-fired:
+		if d < 30*time.Millisecond { t.Fatalf("fired early after %v", d) }
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timer did not fire")
+	}
+
+	var n atomic.Int32
+	tm := w.Every(5*time.Millisecond, func() { n.Add(1) })
+	time.Sleep(60 * time.Millisecond)
+	tm.Stop()
+	if n.Load() < 3 { t.Fatalf("expected periodic task to run, got %d", n.Load()) }
+}
+
+func BenchmarkWheel_AfterFuncStop(b *testing.B) {
+	w := newWheel(time.Millisecond)
+	for i := 0; i < b.N; i++ {
+		w.AfterFunc(time.Duration(i%100000)*time.Millisecond, func() {}).Stop()
+	}
+}